@@ -923,3 +923,7 @@ suiteafinal:
 	INC $0210
 theend:
 	JMP theend
+
+; NMI, RESET and IRQ vectors, all pointing at the start of the suite
+	.ORG $FFFA
+	.WORD start, start, start
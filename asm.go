@@ -0,0 +1,667 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Assembler
+//
+// The asm subcommand is a two pass assembler for the syntax of the bundled AllSuiteA.asm and the six5go2 listings
+// written by the static disassemblers. The first pass works out the address of every label and the second pass
+// writes the code. An instruction whose operand is not known in the first pass, because it refers to a label
+// further down, is assembled with absolute rather than zero page addressing in both passes, so the two passes
+// always agree on the size of every instruction. An operand written with an a: prefix, such as LDA a:$10, is always
+// assembled as absolute, which keeps instructions that a binary encodes as absolute with a small address.
+//
+//	label:			Defines label as the current address
+//	@label:			Local label, private to the code between two ordinary labels or to one macro expansion
+//	- or +			Anonymous label, - refers to the previous one, -- to the one before that, + and ++ look ahead
+//	name = expr		Defines name as the value of expr, .EQU may be used instead of =
+//	.ORG expr		Sets the current address, *= expr does the same
+//	.BYTE expr,"text"	Bytes and text
+//	.WORD expr,expr		Little endian words
+//	.TEXT "text",expr	Text and bytes
+//	.INCLUDE "file"		Assembles another source file, relative to the including file
+//	.INCBIN "file",off,len	Bytes from a binary file, the offset and length are optional
+//	.PROC name ... .ENDPROC	Defines name and scopes the labels inside as name.label
+//
+// Macros, conditionals and repeat blocks are described in asmblocks.go. Mnemonics, directives and register names
+// are not case sensitive, symbols and macro names are. Anything after a ; is a comment.
+
+type assembler struct {
+	pass      int
+	pc        int
+	symbols   map[string]int
+	defined   map[string]bool // Symbols defined so far in this pass
+	wide      []bool          // Whether each instruction with a zero page form was assembled as absolute in the first pass
+	statement int             // Index into wide for the current instruction
+	tests     []bool          // Result of each .IFDEF and .IFNDEF in the first pass
+	test      int             // Index into tests for the current .IFDEF or .IFNDEF
+	scopes    []string        // Names of the enclosing .PROC blocks
+	lastLabel string          // Full name of the last ordinary label, the owner of local labels
+	anonymous []int           // Address of every anonymous label, from the first pass
+	anonCount int             // Anonymous labels defined so far in this pass
+	macros    map[string]*macro
+	expansion int             // Number of macro expansions so far in this pass, names their local label scopes
+	including map[string]bool // Source files being assembled, to catch a file that includes itself
+	listing   bool            // Whether a listing is written
+	listed    []*listingLine  // The listing of the second pass
+	code      [65536]byte
+	low, high int // Range of addresses written
+	errors    []string
+}
+
+// sourceLine is one line of source and where it was written
+type sourceLine struct {
+	file   string
+	number int
+	text   string
+}
+
+var (
+	// instructionModes maps each mnemonic to its addressing modes and their opcodes
+	instructionModes = map[string]map[string]byte{}
+
+	labelDefinition     = regexp.MustCompile(`^(@?[A-Za-z_][A-Za-z0-9_.]*):`)
+	anonymousDefinition = regexp.MustCompile(`^[-+]+(\s|$)`)
+	equateLine          = regexp.MustCompile(`(?i)^(@?[A-Za-z_][A-Za-z0-9_.]*)\s*(=|\.EQU\s)\s*(.*)$`)
+	originLine          = regexp.MustCompile(`^\*\s*=\s*(.*)$`)
+	indirectX           = regexp.MustCompile(`(?i)^\((.*?)\s*,\s*X\s*\)$`)
+	indirectY           = regexp.MustCompile(`(?i)^\((.*)\)\s*,\s*Y$`)
+	indirect            = regexp.MustCompile(`^\((.*)\)$`)
+	indexed             = regexp.MustCompile(`(?i)^(.*?)\s*,\s*([XY])$`)
+)
+
+func init() {
+	for i, info := range opcodes {
+		if info.mnemonic == "" {
+			continue
+		}
+		if instructionModes[info.mnemonic] == nil {
+			instructionModes[info.mnemonic] = map[string]byte{}
+		}
+		instructionModes[info.mnemonic][info.mode] = byte(i)
+	}
+}
+
+// assembleFile assembles the source file and writes the binary and the symbol file named in the options,
+// which default to the source file name with .bin and .sym extensions
+func assembleFile(source string, options []string) {
+	base := strings.TrimSuffix(source, filepath.Ext(source))
+	binaryFile, symbolFile, listFile := base+".bin", base+".sym", ""
+	for _, option := range options {
+		name, value, _ := strings.Cut(option, "=")
+		switch name {
+		case "out":
+			binaryFile = value
+		case "sym":
+			symbolFile = value
+		case "list":
+			listFile = value
+		}
+	}
+
+	a, err := assemble(source, listFile != "")
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(binaryFile, a.code[a.low:a.high+1], 0644); err != nil {
+		fmt.Printf("Cannot write %s: %v\n", binaryFile, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Assembled %d ($%04X) bytes from $%04X to $%04X into %s\n\n", a.high-a.low+1, a.high-a.low+1, a.low, a.high, binaryFile)
+	if err := a.writeSymbols(symbolFile); err != nil {
+		fmt.Printf("Cannot write %s: %v\n", symbolFile, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %d symbols to %s\n\n", len(a.symbols), symbolFile)
+	if a.listing {
+		if err := a.writeListing(listFile); err != nil {
+			fmt.Printf("Cannot write %s: %v\n", listFile, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote the listing to %s\n\n", listFile)
+	}
+}
+
+// assemble runs both passes over the source file, keeping the listing of the second pass when listing is set.
+// The error holds every error of the pass that failed, one to a line.
+func assemble(source string, listing bool) (*assembler, error) {
+	lines, err := readSource(source)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read %s: %v", source, err)
+	}
+	a := &assembler{symbols: map[string]int{}, listing: listing}
+	for a.pass = 1; a.pass <= 2; a.pass++ {
+		a.pc, a.statement, a.test, a.low, a.high = 0, 0, 0, 0x10000, -1
+		a.defined, a.macros = map[string]bool{}, map[string]*macro{}
+		a.scopes, a.lastLabel, a.anonCount, a.expansion = nil, "", 0, 0
+		a.including = map[string]bool{filepath.Clean(source): true}
+		a.assembleSource(lines, 0)
+		if len(a.scopes) > 0 {
+			a.errors = append(a.errors, fmt.Sprintf("%s: .PROC %s is missing .ENDPROC", source, strings.Join(a.scopes, ".")))
+		}
+		if len(a.errors) > 0 {
+			return nil, fmt.Errorf("%s", strings.Join(a.errors, "\n"))
+		}
+	}
+	if a.high < 0 {
+		return nil, fmt.Errorf("%s produced no code", source)
+	}
+	return a, nil
+}
+
+// readSource returns the lines of filename
+func readSource(filename string) ([]sourceLine, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []sourceLine
+	scanner := bufio.NewScanner(f)
+	for number := 1; scanner.Scan(); number++ {
+		lines = append(lines, sourceLine{file: filename, number: number, text: scanner.Text()})
+	}
+	return lines, scanner.Err()
+}
+
+// relativePath resolves a file named in a directive relative to the source file that names it
+func relativePath(line sourceLine, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(filepath.Dir(line.file), name)
+}
+
+// writeSymbols writes every symbol in VICE label format, which the disassemblers read back with sym=
+func (a *assembler) writeSymbols(filename string) error {
+	names := make([]string, 0, len(a.symbols))
+	for name := range a.symbols {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if a.symbols[names[i]] != a.symbols[names[j]] {
+			return a.symbols[names[i]] < a.symbols[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		fmt.Fprintf(f, "al C:%04X .%s\n", a.symbols[name]&0xFFFF, name)
+	}
+	return f.Close()
+}
+
+// qualify returns the full name of a symbol defined here. Local labels belong to the last ordinary label and
+// everything else to the enclosing .PROC blocks.
+func (a *assembler) qualify(name string) string {
+	if strings.HasPrefix(name, "@") {
+		return a.lastLabel + name
+	}
+	if len(a.scopes) == 0 {
+		return name
+	}
+	return strings.Join(a.scopes, ".") + "." + name
+}
+
+// lookup returns the value of a symbol, searching the enclosing .PROC blocks from the innermost outwards
+func (a *assembler) lookup(name string) (int, bool) {
+	if strings.HasPrefix(name, "@") {
+		value, ok := a.symbols[a.lastLabel+name]
+		return value, ok
+	}
+	for i := len(a.scopes); i > 0; i-- {
+		if value, ok := a.symbols[strings.Join(a.scopes[:i], ".")+"."+name]; ok {
+			return value, true
+		}
+	}
+	value, ok := a.symbols[name]
+	return value, ok
+}
+
+// define sets a label or equate. Symbols may only be defined once and labels must not move between the passes.
+func (a *assembler) define(name string, value int, label bool) error {
+	full := a.qualify(name)
+	if label && !strings.HasPrefix(name, "@") {
+		a.lastLabel = full
+	}
+	if l := a.current(); l != nil && label {
+		l.label = full
+	}
+	old, ok := a.symbols[full]
+	switch {
+	case ok && a.pass == 1:
+		return fmt.Errorf("%s is already defined", full)
+	case ok && old != value:
+		return fmt.Errorf("%s changed from $%04X to $%04X between passes", full, old, value)
+	}
+	a.symbols[full] = value
+	a.defined[full] = true
+	return nil
+}
+
+// defineAnonymous defines the next anonymous label at the current address
+func (a *assembler) defineAnonymous() error {
+	if a.pass == 1 {
+		a.anonymous = append(a.anonymous, a.pc)
+	} else if a.anonCount >= len(a.anonymous) || a.anonymous[a.anonCount] != a.pc {
+		return fmt.Errorf("anonymous label moved between passes")
+	}
+	if l := a.current(); l != nil {
+		l.label = fmt.Sprintf("anonymous label at $%04X", a.pc)
+	}
+	a.anonCount++
+	return nil
+}
+
+// anonymousLabel returns the address of an anonymous label reference, - or + repeated count times
+func (a *assembler) anonymousLabel(direction byte, count int) (int, bool, error) {
+	index := a.anonCount - count
+	if direction == '+' {
+		index = a.anonCount + count - 1
+	}
+	if index < 0 {
+		return 0, false, fmt.Errorf("there is no anonymous label %s before this line", strings.Repeat("-", count))
+	}
+	if index >= len(a.anonymous) || a.pass == 1 && index >= a.anonCount {
+		if a.pass == 2 {
+			return 0, false, fmt.Errorf("there is no anonymous label %s after this line", strings.Repeat("+", count))
+		}
+		return 0, false, nil
+	}
+	return a.anonymous[index], true, nil
+}
+
+// stripComment removes a ; comment that is not inside quotes
+func stripComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case '\'':
+			// Skip a character constant so that ';' is not taken as a comment
+			if !quoted && i+2 < len(line) && line[i+2] == '\'' {
+				i += 2
+			}
+		case ';':
+			if !quoted {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// splitList splits a directive operand at the commas that are outside quotes and parentheses
+func splitList(text string) []string {
+	var items []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, strings.TrimSpace(text[start:]))
+}
+
+// splitStatement splits a statement into its upper case first word and the rest
+func splitStatement(text string) (string, string) {
+	word, operand := text, ""
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		word, operand = text[:i], strings.TrimSpace(text[i:])
+	}
+	return strings.ToUpper(word), operand
+}
+
+// quoted returns the text between the quotes of a file name operand
+func quoted(operand string) (string, error) {
+	if len(operand) < 2 || !strings.HasPrefix(operand, "\"") || !strings.HasSuffix(operand, "\"") {
+		return "", fmt.Errorf("expected a quoted file name")
+	}
+	return operand[1 : len(operand)-1], nil
+}
+
+// splitLabel splits the label a statement starts with, if any, from the rest of it
+func splitLabel(text string) (label string, anonymous bool, rest string) {
+	if m := labelDefinition.FindStringSubmatch(text); m != nil {
+		return m[1], false, strings.TrimSpace(text[len(m[0]):])
+	}
+	if anonymousDefinition.MatchString(text) {
+		return "", true, strings.TrimSpace(strings.TrimLeft(text, "-+"))
+	}
+	return "", false, text
+}
+
+// defineLabel defines the label split from a statement at the current address
+func (a *assembler) defineLabel(label string, anonymous bool) error {
+	if anonymous {
+		return a.defineAnonymous()
+	}
+	if label != "" {
+		return a.define(label, a.pc, true)
+	}
+	return nil
+}
+
+// assembleStatement assembles one line that is not part of a block directive
+func (a *assembler) assembleStatement(line sourceLine, depth int) error {
+	label, anonymous, text := splitLabel(strings.TrimSpace(stripComment(line.text)))
+	if err := a.defineLabel(label, anonymous); err != nil {
+		return err
+	}
+	if text == "" {
+		return nil
+	}
+	if m := originLine.FindStringSubmatch(text); m != nil {
+		return a.directive(".ORG", m[1])
+	}
+	if m := equateLine.FindStringSubmatch(text); m != nil {
+		value, known, err := a.evaluate(m[3])
+		if err != nil || !known {
+			// An equate that uses a later symbol is defined in the second pass
+			return err
+		}
+		return a.define(m[1], value, false)
+	}
+	word, operand := splitStatement(text)
+	if m, ok := a.macros[strings.Fields(text)[0]]; ok {
+		return a.expandMacro(m, operand, depth)
+	}
+	switch word {
+	case ".INCLUDE":
+		name, err := quoted(operand)
+		if err != nil {
+			return err
+		}
+		path := relativePath(line, name)
+		if a.including[path] {
+			return fmt.Errorf("%s includes itself", path)
+		}
+		lines, err := readSource(path)
+		if err != nil {
+			return err
+		}
+		if depth >= maxDepth {
+			return fmt.Errorf("includes and macros are nested too deeply")
+		}
+		a.including[path] = true
+		a.assembleSource(lines, depth+1)
+		delete(a.including, path)
+		return nil
+	case ".INCBIN":
+		return a.includeBinary(line, operand)
+	}
+	if strings.HasPrefix(word, ".") {
+		return a.directive(word, operand)
+	}
+	return a.instruction(word, operand)
+}
+
+// includeBinary emits the bytes of a binary file, optionally from an offset and for a length
+func (a *assembler) includeBinary(line sourceLine, operand string) error {
+	items := splitList(operand)
+	name, err := quoted(items[0])
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(relativePath(line, name))
+	if err != nil {
+		return err
+	}
+	var limits []int
+	for _, item := range items[1:] {
+		value, known, err := a.evaluate(item)
+		if err != nil {
+			return err
+		}
+		if !known {
+			return fmt.Errorf("the offset and length must not use symbols defined later")
+		}
+		limits = append(limits, value)
+	}
+	if len(limits) > 0 {
+		if limits[0] < 0 || limits[0] > len(data) {
+			return fmt.Errorf("offset %d is outside %s", limits[0], name)
+		}
+		data = data[limits[0]:]
+	}
+	if len(limits) > 1 {
+		if limits[1] < 0 || limits[1] > len(data) {
+			return fmt.Errorf("length %d runs past the end of %s", limits[1], name)
+		}
+		data = data[:limits[1]]
+	}
+	a.emit(data...)
+	return nil
+}
+
+// directive assembles a directive
+func (a *assembler) directive(name string, operand string) error {
+	switch name {
+	case ".ORG":
+		value, known, err := a.evaluate(operand)
+		if err != nil {
+			return err
+		}
+		if !known {
+			return fmt.Errorf("the origin must not use symbols defined later")
+		}
+		if value < 0 || value > 0xFFFF {
+			return fmt.Errorf("origin $%X is outside memory", value)
+		}
+		a.pc = value
+	case ".BYTE", ".TEXT":
+		for _, item := range splitList(operand) {
+			if len(item) >= 2 && strings.HasPrefix(item, "\"") && strings.HasSuffix(item, "\"") {
+				for _, c := range []byte(item[1 : len(item)-1]) {
+					a.emit(c)
+				}
+				continue
+			}
+			value, err := a.byteValue(item)
+			if err != nil {
+				return err
+			}
+			a.emit(value)
+		}
+	case ".WORD":
+		for _, item := range splitList(operand) {
+			value, known, err := a.evaluate(item)
+			if err != nil {
+				return err
+			}
+			if known && (value < -0x8000 || value > 0xFFFF) {
+				return fmt.Errorf("$%X does not fit in a word", value)
+			}
+			a.emit(byte(value), byte(value>>8))
+		}
+	case ".PROC":
+		if !labelDefinition.MatchString(operand+":") || strings.HasPrefix(operand, "@") {
+			return fmt.Errorf(".PROC needs a name")
+		}
+		if err := a.define(operand, a.pc, true); err != nil {
+			return err
+		}
+		a.scopes = append(a.scopes, operand)
+	case ".ENDPROC":
+		if len(a.scopes) == 0 {
+			return fmt.Errorf(".ENDPROC without .PROC")
+		}
+		a.scopes = a.scopes[:len(a.scopes)-1]
+		a.lastLabel = strings.Join(a.scopes, ".")
+	case ".ENDM", ".ENDR", ".ELSE", ".ENDIF":
+		return fmt.Errorf("%s without the directive that opens its block", name)
+	default:
+		return fmt.Errorf("unknown directive %s", name)
+	}
+	return nil
+}
+
+// byteValue evaluates an expression that must fit in a byte
+func (a *assembler) byteValue(text string) (byte, error) {
+	value, known, err := a.evaluate(text)
+	if err != nil {
+		return 0, err
+	}
+	if known && (value < -0x80 || value > 0xFF) {
+		return 0, fmt.Errorf("$%X does not fit in a byte", value)
+	}
+	return byte(value), nil
+}
+
+// emit writes bytes at the current address in the second pass and advances it in both
+func (a *assembler) emit(values ...byte) {
+	for _, value := range values {
+		if a.pass == 2 && a.pc <= 0xFFFF {
+			a.code[a.pc] = value
+			if l := a.current(); l != nil {
+				l.bytes = append(l.bytes, value)
+			}
+			if a.pc < a.low {
+				a.low = a.pc
+			}
+			if a.pc > a.high {
+				a.high = a.pc
+			}
+		}
+		a.pc++
+	}
+}
+
+// instruction assembles one instruction
+func (a *assembler) instruction(mnemonic string, operand string) error {
+	modes, ok := instructionModes[mnemonic]
+	if !ok {
+		return fmt.Errorf("unknown instruction %s", mnemonic)
+	}
+	switch {
+	case operand == "" || strings.EqualFold(operand, "A"):
+		if opcode, ok := modes[ACCUMULATOR]; ok {
+			a.emit(opcode)
+			a.timing(opcode, 0, "")
+			return nil
+		}
+		if opcode, ok := modes[IMPLIED]; ok && operand == "" {
+			a.emit(opcode)
+			a.timing(opcode, 0, "")
+			return nil
+		}
+		return fmt.Errorf("%s needs an operand", mnemonic)
+	case strings.HasPrefix(operand, "#"):
+		opcode, ok := modes[IMMEDIATE]
+		if !ok {
+			return fmt.Errorf("%s has no immediate mode", mnemonic)
+		}
+		value, err := a.byteValue(operand[1:])
+		if err != nil {
+			return err
+		}
+		a.emit(opcode, value)
+		a.timing(opcode, 0, "")
+		return nil
+	}
+	if opcode, ok := modes[RELATIVE]; ok {
+		target, known, err := a.evaluate(operand)
+		if err != nil {
+			return err
+		}
+		offset := target - (a.pc + 2)
+		if known && (offset < -128 || offset > 127) {
+			return fmt.Errorf("branch to $%04X is out of range", target)
+		}
+		extra, warning := branchTiming(a.pc, target)
+		a.emit(opcode, byte(offset))
+		a.timing(opcode, extra, warning)
+		return nil
+	}
+	if m := indirectX.FindStringSubmatch(operand); m != nil {
+		return a.operand(mnemonic, modes, "", INDIRECTX, m[1])
+	}
+	if m := indirectY.FindStringSubmatch(operand); m != nil {
+		return a.operand(mnemonic, modes, "", INDIRECTY, m[1])
+	}
+	if m := indirect.FindStringSubmatch(operand); m != nil {
+		if _, ok := modes[INDIRECT]; ok {
+			return a.operand(mnemonic, modes, INDIRECT, "", m[1])
+		}
+	}
+	if m := indexed.FindStringSubmatch(operand); m != nil {
+		if strings.EqualFold(m[2], "X") {
+			return a.operand(mnemonic, modes, ABSOLUTEX, ZEROPAGEX, m[1])
+		}
+		return a.operand(mnemonic, modes, ABSOLUTEY, ZEROPAGEY, m[1])
+	}
+	return a.operand(mnemonic, modes, ABSOLUTE, ZEROPAGE, operand)
+}
+
+// operand assembles an instruction with an address operand. Either mode may be empty when the operand can only be
+// 16 or 8 bits. The zero page form is chosen when the instruction has one and the value is known in the first pass
+// and fits in a byte, unless the operand has the a: prefix.
+func (a *assembler) operand(mnemonic string, modes map[string]byte, absolute string, zeroPage string, text string) error {
+	forced := len(text) > 2 && strings.EqualFold(text[:2], "a:")
+	if forced {
+		text = strings.TrimSpace(text[2:])
+	}
+	value, known, err := a.evaluate(text)
+	if err != nil {
+		return err
+	}
+	absoluteOpcode, hasAbsolute := modes[absolute]
+	zeroPageOpcode, hasZeroPage := modes[zeroPage]
+	if !hasAbsolute && !hasZeroPage {
+		return fmt.Errorf("%s does not have that addressing mode", mnemonic)
+	}
+	if forced && !hasAbsolute {
+		return fmt.Errorf("%s has no absolute form of that addressing mode", mnemonic)
+	}
+	wide := !hasZeroPage
+	if hasAbsolute && hasZeroPage {
+		if a.pass == 1 {
+			a.wide = append(a.wide, forced || !known || value < 0 || value > 0xFF)
+		}
+		if a.statement >= len(a.wide) {
+			return fmt.Errorf("phase error: the second pass assembled more instructions than the first")
+		}
+		wide = a.wide[a.statement]
+		a.statement++
+	}
+	if wide {
+		if known && (value < 0 || value > 0xFFFF) {
+			return fmt.Errorf("$%X is outside memory", value)
+		}
+		a.emit(absoluteOpcode, byte(value), byte(value>>8))
+		extra, warning := indexedTiming(absoluteOpcode, value)
+		a.timing(absoluteOpcode, extra, warning)
+		return nil
+	}
+	if known && (value < 0 || value > 0xFF) {
+		return fmt.Errorf("$%X is not a zero page address", value)
+	}
+	a.emit(zeroPageOpcode, byte(value))
+	extra, warning := indexedTiming(zeroPageOpcode, value)
+	a.timing(zeroPageOpcode, extra, warning)
+	return nil
+}
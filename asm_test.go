@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// assembleText assembles source from a temporary file and returns the bytes written
+func assembleText(t *testing.T, source string) []byte {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "test.s")
+	if err := os.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := assemble(filename, false)
+	if err != nil {
+		t.Fatalf("assembling %q: %v", source, err)
+	}
+	return a.code[a.low : a.high+1]
+}
+
+func TestAssembleForcedAbsolute(t *testing.T) {
+	tests := []struct {
+		source string
+		code   []byte
+	}{
+		{"LDA $10", []byte{0xA5, 0x10}},
+		{"LDA a:$10", []byte{0xAD, 0x10, 0x00}},
+		{"LDA A:$0010", []byte{0xAD, 0x10, 0x00}},
+		{"STA a:$10,X", []byte{0x9D, 0x10, 0x00}},
+		{"LDX a:$20,Y", []byte{0xBE, 0x20, 0x00}},
+		{"JMP a:$0030", []byte{0x4C, 0x30, 0x00}},
+		{"LDA a:zp\nzp = $42", []byte{0xAD, 0x42, 0x00}},
+	}
+	for _, test := range tests {
+		if code := assembleText(t, " *= $1000\n"+test.source+"\n"); !bytes.Equal(code, test.code) {
+			t.Errorf("%q assembled to % X, want % X", test.source, code, test.code)
+		}
+	}
+}
+
+func TestAssembleForcedAbsoluteWithoutAbsoluteMode(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.s")
+	if err := os.WriteFile(filename, []byte("LDA (a:$10),Y\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := assemble(filename, false); err == nil {
+		t.Errorf("a: on an indirect indexed operand assembled without an error")
+	}
+}
+
+func TestAssembleAllSuiteA(t *testing.T) {
+	want, err := os.ReadFile("AllSuiteA.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := assemble("AllSuiteA.asm", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.low != 0x4000 {
+		t.Errorf("AllSuiteA.asm starts at $%04X, want $4000", a.low)
+	}
+	code := a.code[a.low : a.high+1]
+	if len(code) != len(want) {
+		t.Fatalf("AllSuiteA.asm assembled to %d bytes, AllSuiteA.bin has %d", len(code), len(want))
+	}
+	for i := range code {
+		if code[i] != want[i] {
+			t.Fatalf("AllSuiteA.asm differs from AllSuiteA.bin at $%04X: $%02X, want $%02X", a.low+i, code[i], want[i])
+		}
+	}
+}
+
+func TestAssembleLabelledBlocks(t *testing.T) {
+	tests := []struct {
+		source string
+		code   []byte
+	}{
+		{"loop: .IF 1\n NOP\n .ENDIF\n JMP loop", []byte{0xEA, 0x4C, 0x00, 0x10}},
+		{"skip: .IF 0\n NOP\n .ENDIF\n JMP skip", []byte{0x4C, 0x00, 0x10}},
+		{" NOP\nflag: .IFDEF flag\n NOP\n .ENDIF\n JMP flag", []byte{0xEA, 0xEA, 0x4C, 0x01, 0x10}},
+		{"table: .REPT 3,i\n .BYTE \\i\n .ENDR\n .WORD table", []byte{0x00, 0x01, 0x02, 0x00, 0x10}},
+		{"+ .REPT 3,i\n .BYTE \\i\n .ENDR\n JMP -", []byte{0x00, 0x01, 0x02, 0x4C, 0x00, 0x10}},
+		{"here: .MACRO twice\n NOP\n NOP\n .ENDM\n twice\n JMP here", []byte{0xEA, 0xEA, 0x4C, 0x00, 0x10}},
+		{"outer: .REPT 2\n NOP\n .ENDR\n JMP outer", []byte{0xEA, 0xEA, 0x4C, 0x00, 0x10}},
+	}
+	for _, test := range tests {
+		if code := assembleText(t, " *= $1000\n"+test.source+"\n"); !bytes.Equal(code, test.code) {
+			t.Errorf("%q assembled to % X, want % X", test.source, code, test.code)
+		}
+	}
+}
+
+func TestAssembleLabelledBlockSkipped(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.s")
+	source := " .IF 0\nhidden: .IF 1\n .ENDIF\n .ENDIF\n .BYTE hidden\n"
+	if err := os.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := assemble(filename, false); err == nil {
+		t.Errorf("a label on a block inside a false .IF was defined")
+	}
+}
+
+func TestAssembleIfdefLaterEquate(t *testing.T) {
+	// x is only defined in the second pass, which must still take the branch the first pass took
+	source := " *= $1000\nx = later\n .IFDEF x\n LDA $10\n .ENDIF\nlater: NOP\n"
+	if code := assembleText(t, source); !bytes.Equal(code, []byte{0xEA}) {
+		t.Errorf("%q assembled to % X, want EA", source, code)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Assembler blocks
+//
+// Macros, conditionals and repeat blocks span several lines and are handled before a line reaches the statement
+// assembler.
+//
+//	.MACRO name a,b ... .ENDM	Defines a macro, \a and \b in the body are replaced by the arguments
+//	name 1,2			Expands a macro, local labels inside each expansion are private to it
+//	.IF expr ... .ELSE ... .ENDIF	Assembles the lines when expr is not zero, .ELSE is optional
+//	.IFDEF name, .IFNDEF name	Tests whether a symbol is defined above this line
+//	.REPT count,i ... .ENDR		Repeats the lines count times, \i in the body is replaced by the repeat number
+//
+// Conditions and repeat counts must be known in the first pass, so that both passes assemble the same lines.
+
+// maxDepth limits the nesting of includes and macro expansions, which catches a file or macro that uses itself
+const maxDepth = 32
+
+type macro struct {
+	name       string
+	parameters []string
+	body       []sourceLine
+}
+
+// condition is one open .IF block
+type condition struct {
+	enclosing bool // Whether the lines around the block are assembled
+	active    bool // Whether the lines in the current branch are assembled
+	taken     bool // Whether a branch has been assembled, so .ELSE is skipped
+}
+
+// blockDirectives are the directives handled by assembleSource rather than assembleStatement
+var blockDirectives = map[string]bool{
+	".IF": true, ".IFDEF": true, ".IFNDEF": true, ".ELSE": true, ".ENDIF": true, ".MACRO": true, ".REPT": true,
+}
+
+// parameter matches a macro parameter or repeat counter in a body line
+var parameter = regexp.MustCompile(`\\([A-Za-z_][A-Za-z0-9_]*)`)
+
+// assembleSource assembles lines in order, recording the errors against the line that caused them
+func (a *assembler) assembleSource(lines []sourceLine, depth int) {
+	var conditions []condition
+	active := func() bool {
+		return len(conditions) == 0 || conditions[len(conditions)-1].active
+	}
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		label, anonymous, text := splitLabel(strings.TrimSpace(stripComment(line.text)))
+		word, operand := splitStatement(text)
+		var err error
+		a.listLine(line, active())
+		// A label in front of a block directive takes the address the block starts at. Other lines define their
+		// own labels when they are assembled.
+		if blockDirectives[word] && active() {
+			if err := a.defineLabel(label, anonymous); err != nil {
+				a.errors = append(a.errors, fmt.Sprintf("%s line %d: %v", line.file, line.number, err))
+			}
+		}
+		switch word {
+		case ".IF", ".IFDEF", ".IFNDEF":
+			c := condition{enclosing: active()}
+			if c.enclosing {
+				c.active, err = a.condition(word, operand)
+				c.taken = c.active
+			}
+			conditions = append(conditions, c)
+		case ".ELSE":
+			if len(conditions) == 0 {
+				err = fmt.Errorf(".ELSE without .IF")
+				break
+			}
+			c := &conditions[len(conditions)-1]
+			c.active = c.enclosing && !c.taken
+			c.taken = true
+		case ".ENDIF":
+			if len(conditions) == 0 {
+				err = fmt.Errorf(".ENDIF without .IF")
+				break
+			}
+			conditions = conditions[:len(conditions)-1]
+		default:
+			if !active() {
+				continue
+			}
+			switch word {
+			case ".MACRO":
+				var body []sourceLine
+				body, i, err = collectBlock(lines, i, ".MACRO", ".ENDM")
+				if err == nil {
+					err = a.defineMacro(operand, body)
+				}
+				// The definition is listed as written, its lines are assembled where the macro is used
+				for _, l := range body {
+					a.listLine(l, false)
+				}
+				if i < len(lines) {
+					a.listLine(lines[i], false)
+				}
+			case ".REPT":
+				var body []sourceLine
+				body, i, err = collectBlock(lines, i, ".REPT", ".ENDR")
+				if err == nil {
+					err = a.repeat(operand, body, depth)
+					a.listLine(lines[i], true)
+				}
+			default:
+				err = a.assembleStatement(line, depth)
+			}
+		}
+		if err != nil {
+			a.errors = append(a.errors, fmt.Sprintf("%s line %d: %v", line.file, line.number, err))
+		}
+		if a.pc > 0x10000 {
+			a.errors = append(a.errors, fmt.Sprintf("%s line %d: code runs past $FFFF", line.file, line.number))
+			a.pc = 0x10000
+		}
+	}
+	if len(conditions) > 0 && len(lines) > 0 {
+		last := lines[len(lines)-1]
+		a.errors = append(a.errors, fmt.Sprintf("%s line %d: .IF is missing .ENDIF", last.file, last.number))
+	}
+}
+
+// collectBlock returns the lines between the opening directive at lines[start] and its closing directive, and the
+// index of the closing directive. Blocks of the same kind may be nested.
+func collectBlock(lines []sourceLine, start int, open string, close string) ([]sourceLine, int, error) {
+	nesting := 0
+	for i := start + 1; i < len(lines); i++ {
+		_, _, text := splitLabel(strings.TrimSpace(stripComment(lines[i].text)))
+		word, _ := splitStatement(text)
+		switch word {
+		case open:
+			nesting++
+		case close:
+			if nesting == 0 {
+				return lines[start+1 : i], i, nil
+			}
+			nesting--
+		}
+	}
+	return nil, len(lines), fmt.Errorf("%s is missing %s", open, close)
+}
+
+// condition evaluates the operand of an .IF, .IFDEF or .IFNDEF
+func (a *assembler) condition(directive string, operand string) (bool, error) {
+	if directive != ".IF" {
+		// Only symbols defined above in the first pass count. An equate that uses a later symbol is only defined
+		// in the second pass, so the second pass takes the branch the first one took.
+		if a.pass == 1 {
+			defined := a.defined[a.qualify(operand)] || a.defined[operand]
+			a.tests = append(a.tests, defined == (directive == ".IFDEF"))
+		} else if a.test >= len(a.tests) {
+			return false, fmt.Errorf("phase error: the second pass reached more %s blocks than the first", directive)
+		}
+		a.test++
+		return a.tests[a.test-1], nil
+	}
+	value, known, err := a.evaluate(operand)
+	if err != nil {
+		return false, err
+	}
+	if !known {
+		return false, fmt.Errorf("the condition must not use symbols defined later")
+	}
+	return value != 0, nil
+}
+
+// defineMacro records a macro from its .MACRO operand and body
+func (a *assembler) defineMacro(operand string, body []sourceLine) error {
+	name, rest := operand, ""
+	if i := strings.IndexAny(operand, " \t"); i >= 0 {
+		name, rest = operand[:i], strings.TrimSpace(operand[i:])
+	}
+	if name == "" {
+		return fmt.Errorf(".MACRO needs a name")
+	}
+	if _, ok := instructionModes[strings.ToUpper(name)]; ok {
+		return fmt.Errorf("macro %s has the name of an instruction", name)
+	}
+	if _, ok := a.macros[name]; ok {
+		return fmt.Errorf("macro %s is already defined", name)
+	}
+	m := &macro{name: name, body: body}
+	if rest != "" {
+		for _, p := range splitList(rest) {
+			m.parameters = append(m.parameters, strings.TrimPrefix(p, "\\"))
+		}
+	}
+	a.macros[name] = m
+	return nil
+}
+
+// substitute replaces every \name in the lines that has a value. Other references are left for an inner repeat
+// block, or are reported when the line is assembled.
+func substitute(lines []sourceLine, values map[string]string) []sourceLine {
+	expanded := make([]sourceLine, len(lines))
+	for i, line := range lines {
+		line.text = parameter.ReplaceAllStringFunc(line.text, func(reference string) string {
+			if value, ok := values[reference[1:]]; ok {
+				return value
+			}
+			return reference
+		})
+		expanded[i] = line
+	}
+	return expanded
+}
+
+// expandMacro assembles a macro body with the arguments in operand
+func (a *assembler) expandMacro(m *macro, operand string, depth int) error {
+	var arguments []string
+	if operand != "" {
+		arguments = splitList(operand)
+	}
+	if len(arguments) != len(m.parameters) {
+		return fmt.Errorf("macro %s takes %d arguments, not %d", m.name, len(m.parameters), len(arguments))
+	}
+	if depth >= maxDepth {
+		return fmt.Errorf("includes and macros are nested too deeply")
+	}
+	values := map[string]string{}
+	for i, p := range m.parameters {
+		values[p] = arguments[i]
+	}
+	body := substitute(m.body, values)
+	// Local labels inside the expansion belong to it alone
+	outer := a.lastLabel
+	a.expansion++
+	a.lastLabel = fmt.Sprintf("%s_%d", m.name, a.expansion)
+	a.assembleSource(body, depth+1)
+	a.lastLabel = outer
+	return nil
+}
+
+// repeat assembles the body of a .REPT block the number of times given in its operand
+func (a *assembler) repeat(operand string, body []sourceLine, depth int) error {
+	items := splitList(operand)
+	count, known, err := a.evaluate(items[0])
+	if err != nil {
+		return err
+	}
+	if !known {
+		return fmt.Errorf("the repeat count must not use symbols defined later")
+	}
+	counter := ""
+	if len(items) > 1 {
+		counter = strings.TrimPrefix(items[1], "\\")
+	}
+	for i := 0; i < count; i++ {
+		values := map[string]string{}
+		if counter != "" {
+			values[counter] = strconv.Itoa(i)
+		}
+		a.assembleSource(substitute(body, values), depth)
+	}
+	return nil
+}
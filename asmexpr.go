@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Assembler expressions
+//
+// Operands are integer expressions made of numbers ($hex, %binary, decimal or a 'c' character), symbols, * for the
+// current address, anonymous label references and parentheses. The unary operators are - for negation, ~ for
+// complement, ! for logical not, < for the low byte and > for the high byte, and bind tighter than any binary
+// operator, so <table+1 is the low byte of table plus one. The binary operators from lowest to highest precedence
+// are ||, &&, the comparisons = == != <> < > <= >=, then |, ^, &, << and >>, + and -, and *, / and %.
+// Comparisons and the logical operators give 1 for true and 0 for false.
+
+// binaryOperators lists the binary operators by precedence, longer operators first within a level
+var binaryOperators = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<>", "<=", ">=", "=", "<", ">"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// anonymousReference matches a run of - or + that stands alone as a reference to an anonymous label
+var anonymousReference = regexp.MustCompile(`^(-+|\++)\s*($|[,)])`)
+
+// expressionScope supplies the values of the names in an expression. The assembler supplies its symbols, and the
+// emulator supplies the registers and memory for breakpoint conditions.
+type expressionScope interface {
+	// symbol returns the value of name and whether it is known yet
+	symbol(name string) (int, bool, error)
+	// element returns name[index]
+	element(name string, index int) (int, error)
+	// currentAddress returns the value of *
+	currentAddress() int
+	// anonymousLabel returns the address of an anonymous label reference, - or + repeated count times
+	anonymousLabel(direction byte, count int) (int, bool, error)
+}
+
+// expression evaluates one expression. A value is known when every symbol it uses has been defined, which in the
+// first pass is only true for symbols defined earlier in the source.
+type expression struct {
+	scope expressionScope
+	text  string
+	pos   int
+	known bool
+}
+
+// evaluate returns the value of text and whether it is known yet
+func (a *assembler) evaluate(text string) (int, bool, error) {
+	return evaluateExpression(a, text)
+}
+
+// evaluateExpression returns the value of text in scope and whether it is known yet
+func evaluateExpression(scope expressionScope, text string) (int, bool, error) {
+	e := &expression{scope: scope, text: text, known: true}
+	value, err := e.binary(0)
+	if err != nil {
+		return 0, false, err
+	}
+	e.skipSpace()
+	if e.pos < len(e.text) {
+		return 0, false, fmt.Errorf("unexpected %q in expression %s", e.text[e.pos:], text)
+	}
+	return value, e.known, nil
+}
+
+func (e *expression) skipSpace() {
+	for e.pos < len(e.text) && (e.text[e.pos] == ' ' || e.text[e.pos] == '\t') {
+		e.pos++
+	}
+}
+
+// operator returns the binary operator of the given precedence at the current position, if there is one
+func (e *expression) operator(precedence int) string {
+	e.skipSpace()
+	rest := e.text[e.pos:]
+	for _, op := range binaryOperators[precedence] {
+		// A single | or & must not be the start of || or &&
+		if strings.HasPrefix(rest, op) && !(len(op) == 1 && len(rest) > 1 && rest[1] == op[0] && (op == "|" || op == "&")) {
+			return op
+		}
+	}
+	return ""
+}
+
+// truth returns 1 for true and 0 for false
+func truth(condition bool) int {
+	if condition {
+		return 1
+	}
+	return 0
+}
+
+// binary parses operators of the given precedence and higher
+func (e *expression) binary(precedence int) (int, error) {
+	if precedence == len(binaryOperators) {
+		return e.unary()
+	}
+	left, err := e.binary(precedence + 1)
+	if err != nil {
+		return 0, err
+	}
+	for op := e.operator(precedence); op != ""; op = e.operator(precedence) {
+		e.pos += len(op)
+		right, err := e.binary(precedence + 1)
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "||":
+			left = truth(left != 0 || right != 0)
+		case "&&":
+			left = truth(left != 0 && right != 0)
+		case "==", "=":
+			left = truth(left == right)
+		case "!=", "<>":
+			left = truth(left != right)
+		case "<=":
+			left = truth(left <= right)
+		case ">=":
+			left = truth(left >= right)
+		case "<":
+			left = truth(left < right)
+		case ">":
+			left = truth(left > right)
+		case "|":
+			left |= right
+		case "^":
+			left ^= right
+		case "&":
+			left &= right
+		case "<<":
+			left <<= uint(right)
+		case ">>":
+			left >>= uint(right)
+		case "+":
+			left += right
+		case "-":
+			left -= right
+		case "*":
+			left *= right
+		case "/", "%":
+			if right == 0 {
+				// An unknown divisor is zero in the first pass
+				if e.known {
+					return 0, fmt.Errorf("division by zero in %s", e.text)
+				}
+				left = 0
+			} else if op == "/" {
+				left /= right
+			} else {
+				left %= right
+			}
+		}
+	}
+	return left, nil
+}
+
+func (e *expression) unary() (int, error) {
+	e.skipSpace()
+	if e.pos >= len(e.text) {
+		return 0, fmt.Errorf("missing value in expression %s", e.text)
+	}
+	if m := anonymousReference.FindStringSubmatch(e.text[e.pos:]); m != nil {
+		e.pos += len(m[1])
+		value, known, err := e.scope.anonymousLabel(m[1][0], len(m[1]))
+		if !known {
+			e.known = false
+		}
+		return value, err
+	}
+	switch e.text[e.pos] {
+	case '-', '~', '!', '<', '>':
+		op := e.text[e.pos]
+		e.pos++
+		value, err := e.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '-':
+			return -value, nil
+		case '~':
+			return ^value, nil
+		case '!':
+			return truth(value == 0), nil
+		case '<':
+			return value & 0xFF, nil
+		}
+		return value >> 8 & 0xFF, nil
+	}
+	return e.primary()
+}
+
+func (e *expression) primary() (int, error) {
+	c := e.text[e.pos]
+	switch {
+	case c == '(':
+		e.pos++
+		value, err := e.binary(0)
+		if err != nil {
+			return 0, err
+		}
+		e.skipSpace()
+		if e.pos >= len(e.text) || e.text[e.pos] != ')' {
+			return 0, fmt.Errorf("missing ) in expression %s", e.text)
+		}
+		e.pos++
+		return value, nil
+	case c == '*':
+		e.pos++
+		return e.scope.currentAddress(), nil
+	case c == '\'':
+		if e.pos+2 >= len(e.text) || e.text[e.pos+2] != '\'' {
+			return 0, fmt.Errorf("bad character constant in %s", e.text)
+		}
+		e.pos += 3
+		return int(e.text[e.pos-2]), nil
+	case c == '$' || c == '%' || c >= '0' && c <= '9':
+		start := e.pos
+		e.pos++
+		for e.pos < len(e.text) && isHexDigit(e.text[e.pos]) {
+			e.pos++
+		}
+		value, err := parseNumber(e.text[start:e.pos])
+		if err != nil {
+			return 0, fmt.Errorf("bad number %s", e.text[start:e.pos])
+		}
+		return value, nil
+	case isIdentifierStart(c):
+		start := e.pos
+		for e.pos < len(e.text) && isIdentifierChar(e.text[e.pos]) {
+			e.pos++
+		}
+		name := e.text[start:e.pos]
+		if e.pos < len(e.text) && e.text[e.pos] == '[' {
+			e.pos++
+			index, err := e.binary(0)
+			if err != nil {
+				return 0, err
+			}
+			e.skipSpace()
+			if e.pos >= len(e.text) || e.text[e.pos] != ']' {
+				return 0, fmt.Errorf("missing ] in expression %s", e.text)
+			}
+			e.pos++
+			return e.scope.element(name, index)
+		}
+		value, known, err := e.scope.symbol(name)
+		if !known {
+			e.known = false
+		}
+		return value, err
+	}
+	return 0, fmt.Errorf("unexpected %q in expression %s", e.text[e.pos:], e.text)
+}
+
+// symbol returns the value of a label or equate, which is an error only once every symbol has been defined
+func (a *assembler) symbol(name string) (int, bool, error) {
+	value, ok := a.lookup(name)
+	if !ok && a.pass == 2 {
+		return 0, false, fmt.Errorf("undefined symbol %s", name)
+	}
+	return value, ok, nil
+}
+
+func (a *assembler) element(name string, index int) (int, error) {
+	return 0, fmt.Errorf("%s[] is only available in breakpoint conditions", name)
+}
+
+func (a *assembler) currentAddress() int {
+	return a.pc
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'F' || c >= 'a' && c <= 'f' || c == 'x' || c == 'X'
+}
+
+func isIdentifierStart(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '_' || c == '@'
+}
+
+func isIdentifierChar(c byte) bool {
+	return isIdentifierStart(c) || c >= '0' && c <= '9' || c == '.'
+}
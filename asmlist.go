@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Assembler listing
+//
+// With list= the assembler writes a listing of the second pass. Each line shows the address, the bytes it emitted,
+// the base cycle count of an instruction with any extra cycles it may take, and the source line after macro and
+// repeat expansion. Branches that cross a page and indexed reads that may cross one are flagged, and every block of
+// code from one label to the next is followed by its total cycle count, which is what matters for timed loops.
+
+type listingLine struct {
+	line    sourceLine
+	address int    // -1 when the line was not assembled
+	label   string // Label defined on the line, which starts a new block
+	bytes   []byte
+	cycles  int    // Base cycles, 0 for lines that are not instructions
+	extra   int    // Extra cycles the instruction may take
+	warning string // Page crossing warning
+}
+
+// listLine starts the listing entry for line, which collects what the line emits until the next line starts
+func (a *assembler) listLine(line sourceLine, assembled bool) {
+	if !a.listing || a.pass != 2 {
+		return
+	}
+	address := a.pc
+	if !assembled {
+		address = -1
+	}
+	a.listed = append(a.listed, &listingLine{line: line, address: address})
+}
+
+// current returns the listing entry of the line being assembled, nil when no listing is written
+func (a *assembler) current() *listingLine {
+	if !a.listing || a.pass != 2 || len(a.listed) == 0 {
+		return nil
+	}
+	return a.listed[len(a.listed)-1]
+}
+
+// timing records the cycles of the instruction just assembled with opcode
+func (a *assembler) timing(opcode byte, extra int, warning string) {
+	if l := a.current(); l != nil {
+		l.cycles, l.extra, l.warning = opcodes[opcode].cycles, extra, warning
+	}
+}
+
+// branchTiming returns the extra cycles of a branch from address to target and a warning when it crosses a page
+func branchTiming(address int, target int) (int, string) {
+	if (address+2)&0xFF00 != target&0xFF00 {
+		return 2, "branch crosses a page, 2 extra cycles when taken"
+	}
+	return 1, ""
+}
+
+// indexedTiming returns the extra cycles of an indexed read and a warning when it may cross a page.
+// An absolute base at the start of a page never crosses, the pointer of (zp),Y is only known at run time.
+func indexedTiming(opcode byte, base int) (int, string) {
+	info := opcodes[opcode]
+	if info.pageCrossCycles == 0 || info.mode != INDIRECTY && base&0xFF == 0 {
+		return 0, ""
+	}
+	return info.pageCrossCycles, "may cross a page, 1 extra cycle"
+}
+
+// writeListing writes the listing of the second pass to filename
+func (a *assembler) writeListing(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(f, "%-4s  %-11s  %-5s  %s\n\n", "ADDR", "BYTES", "CYC", "SOURCE")
+	blockName, blockCycles, blockExtra, instructions := "", 0, 0, 0
+	endBlock := func() {
+		if instructions > 0 {
+			fmt.Fprintf(f, "%26s; %s: %d cycles", "", blockName, blockCycles)
+			if blockExtra > 0 {
+				fmt.Fprintf(f, ", up to %d with taken branches and page crossings", blockCycles+blockExtra)
+			}
+			fmt.Fprintf(f, "\n\n")
+		}
+		blockCycles, blockExtra, instructions = 0, 0, 0
+	}
+	for _, l := range a.listed {
+		if l.label != "" {
+			endBlock()
+			blockName = l.label
+		}
+		address := ""
+		if l.address >= 0 && (len(l.bytes) > 0 || l.label != "") {
+			address = fmt.Sprintf("%04X", l.address)
+		}
+		cycles := ""
+		if l.cycles > 0 {
+			cycles = fmt.Sprint(l.cycles)
+			if l.extra > 0 {
+				cycles += fmt.Sprintf("+%d", l.extra)
+			}
+			blockCycles += l.cycles
+			blockExtra += l.extra
+			instructions++
+		}
+		text := fmt.Sprintf("%-4s  %-11s  %-5s  %s", address, listBytes(l.bytes, 0), cycles, l.line.text)
+		fmt.Fprintf(f, "%s\n", strings.TrimRight(text, " \t\r"))
+		// Data longer than four bytes continues on the following lines
+		for i := 4; i < len(l.bytes); i += 4 {
+			fmt.Fprintf(f, "%04X  %s\n", l.address+i, listBytes(l.bytes, i))
+		}
+		if l.warning != "" {
+			fmt.Fprintf(f, "%26s; ^ %s\n", "", l.warning)
+		}
+	}
+	endBlock()
+	return f.Close()
+}
+
+// listBytes returns up to four bytes from start as hex
+func listBytes(data []byte, start int) string {
+	var values []string
+	for i := start; i < len(data) && i < start+4; i++ {
+		values = append(values, fmt.Sprintf("%02X", data[i]))
+	}
+	return strings.Join(values, " ")
+}
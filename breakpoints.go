@@ -0,0 +1,408 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Breakpoints
+//
+// A breakpoint stops the emulator in the monitor before the instruction at its address runs, or at any address of
+// its range as the checkpoints of the VICE binary monitor may cover one. It may carry a condition, an expression
+// with the operators of the assembler evaluated against the machine: A, X, Y, SP, PC and SR, the flags N, V, B, D,
+// I, Z and C, the cycles and instructions executed so far, mem[address] and any label or symbol name. Numbers in
+// conditions are decimal unless written with $ or %. Only hits where the condition holds are counted, a breakpoint
+// may wait for a number of hits before it first stops and may ignore its next hits. Hit counts are decimal, as are all counts in the monitor.
+//
+// A watchpoint is a breakpoint on a range of memory that is hit when an instruction reads it, writes it, or writes
+// a different value to it. Its condition may also use value, the byte read or written, old, the byte before a
+// write, and address. The access is reported with the instruction that made it and the emulator stops in the
+// monitor once that instruction has finished. Breakpoints and watchpoints share their numbers.
+
+type breakpoint struct {
+	number    int
+	kind      string // exec for a breakpoint, read, write, change or access for a watchpoint
+	address   int
+	end       int    // Last address a watchpoint or a breakpoint range covers
+	condition string // Empty for an unconditional breakpoint
+	after     int    // Stop from this hit on
+	ignore    int    // Hits still to pass without stopping
+	hits      int
+	enabled   bool
+	commands  []string // Monitor commands run when it stops the emulator
+}
+
+var (
+	breakpoints       []*breakpoint
+	breakpointSet     [65536]bool // Addresses with an enabled breakpoint, so other instructions are passed quickly
+	watchSet          [65536]bool // Addresses with an enabled watchpoint, so other accesses are passed quickly
+	nextBreakpoint    = 1
+	breakpointOptions []string    // Monitor commands from break= and watch= on the command line
+	watchStop         *breakpoint // Watchpoint that stops the emulator before the next instruction
+	watchKinds        = []string{"read", "write", "change", "access"}
+)
+
+// machineScope evaluates breakpoint conditions against the registers and memory, and watchpoint conditions also
+// against the access
+type machineScope struct {
+	access  bool
+	address int
+	value   byte
+	old     byte
+}
+
+func (s machineScope) symbol(name string) (int, bool, error) {
+	if s.access {
+		switch name {
+		case "value":
+			return int(s.value), true, nil
+		case "old":
+			return int(s.old), true, nil
+		case "address":
+			return s.address, true, nil
+		}
+	}
+	switch strings.ToUpper(name) {
+	case "A":
+		return int(A), true, nil
+	case "X":
+		return int(X), true, nil
+	case "Y":
+		return int(Y), true, nil
+	case "SP":
+		return int(SP & 0xFF), true, nil
+	case "PC":
+		return bytecounter, true, nil
+	case "SR", "P":
+		return int(SR), true, nil
+	case "N":
+		return int(getSRBit(7)), true, nil
+	case "V":
+		return int(getSRBit(6)), true, nil
+	case "B":
+		return int(getSRBit(4)), true, nil
+	case "D":
+		return int(getSRBit(3)), true, nil
+	case "I":
+		return int(getSRBit(2)), true, nil
+	case "Z":
+		return int(getSRBit(1)), true, nil
+	case "C":
+		return int(getSRBit(0)), true, nil
+	}
+	switch name {
+	case "cycles":
+		return cycleCount, true, nil
+	case "instructions":
+		return instructionCounter, true, nil
+	}
+	if address, ok := addressOf(name); ok {
+		return address, true, nil
+	}
+	return 0, false, fmt.Errorf("unknown name %s, hex numbers are written with $", name)
+}
+
+func (machineScope) element(name string, index int) (int, error) {
+	if strings.ToLower(name) != "mem" {
+		return 0, fmt.Errorf("unknown array %s, memory is mem[address]", name)
+	}
+	return int(memory[index&0xFFFF]), nil
+}
+
+func (machineScope) currentAddress() int {
+	return bytecounter
+}
+
+func (machineScope) anonymousLabel(direction byte, count int) (int, bool, error) {
+	return 0, false, fmt.Errorf("anonymous labels are only available in the assembler")
+}
+
+// addBreakpoint adds a breakpoint from arguments of the form address [after count] [ignore count] [if condition],
+// or a watchpoint from [kind] start [end] followed by the same options
+func addBreakpoint(watch bool, args []string) (*breakpoint, error) {
+	usage := "usage: bk address [after count] [ignore count] [if condition]"
+	b := &breakpoint{kind: "exec", enabled: true}
+	if watch {
+		usage = "usage: w [read|write|change|access] start [end] [after count] [ignore count] [if condition]"
+		b.kind = "access"
+		if len(args) > 0 && contains(watchKinds, strings.ToLower(args[0])) {
+			b.kind, args = strings.ToLower(args[0]), args[1:]
+		}
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s", usage)
+	}
+	var err error
+	if b.address, err = monitorValue(args[0]); err != nil {
+		return nil, err
+	}
+	b.end, args = b.address, args[1:]
+	if watch && len(args) > 0 && !contains([]string{"after", "ignore", "if"}, strings.ToLower(args[0])) {
+		if b.end, err = monitorValue(args[0]); err != nil {
+			return nil, err
+		}
+		if b.end < b.address {
+			return nil, fmt.Errorf("the range ends before it starts")
+		}
+		args = args[1:]
+	}
+	for i := 0; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "after", "ignore":
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("%s needs a count", args[i])
+			}
+			count, err := monitorCount(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if strings.ToLower(args[i]) == "after" {
+				b.after = count
+			} else {
+				b.ignore = count
+			}
+			i++
+		case "if":
+			b.condition = strings.Join(args[i+1:], " ")
+			if _, _, err := evaluateExpression(machineScope{access: watch}, b.condition); err != nil {
+				return nil, err
+			}
+			i = len(args)
+		default:
+			return nil, fmt.Errorf("unexpected %s, expected after, ignore or if", args[i])
+		}
+	}
+	b.number = nextBreakpoint
+	nextBreakpoint++
+	breakpoints = append(breakpoints, b)
+	indexBreakpoints()
+	return b, nil
+}
+
+// contains reports whether list holds text
+func contains(list []string, text string) bool {
+	for _, item := range list {
+		if item == text {
+			return true
+		}
+	}
+	return false
+}
+
+// indexBreakpoints marks the addresses of the enabled breakpoints and watchpoints
+func indexBreakpoints() {
+	breakpointSet, watchSet = [65536]bool{}, [65536]bool{}
+	for _, b := range breakpoints {
+		if !b.enabled {
+			continue
+		}
+		for address := b.address; address <= b.end; address++ {
+			if b.kind == "exec" {
+				breakpointSet[address] = true
+			} else {
+				watchSet[address] = true
+			}
+		}
+	}
+}
+
+// findBreakpoint returns the breakpoint with the number in text
+func findBreakpoint(text string) (*breakpoint, error) {
+	var number int
+	if _, err := fmt.Sscanf(text, "%d", &number); err == nil {
+		for _, b := range breakpoints {
+			if b.number == number {
+				return b, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no breakpoint %s", text)
+}
+
+// deleteBreakpoint removes a breakpoint or watchpoint
+func deleteBreakpoint(b *breakpoint) {
+	for i := range breakpoints {
+		if breakpoints[i] == b {
+			breakpoints = append(breakpoints[:i], breakpoints[i+1:]...)
+			break
+		}
+	}
+	indexBreakpoints()
+}
+
+// breakpointHit counts a hit on every enabled breakpoint at bytecounter whose condition holds and returns the first
+// one that stops the emulator
+func breakpointHit() *breakpoint {
+	if !breakpointSet[bytecounter] {
+		return nil
+	}
+	var stop *breakpoint
+	for _, b := range breakpoints {
+		if b.kind == "exec" && b.address <= bytecounter && bytecounter <= b.end && b.enabled && b.hit(machineScope{}) && stop == nil {
+			stop = b
+		}
+	}
+	return stop
+}
+
+// checkWatchpoints counts a hit on every enabled watchpoint that matches an access and reports the ones that stop
+// the emulator. The emulator stops before the next instruction, as the access is in the middle of one.
+func checkWatchpoints(access string, address int, value byte, old byte) {
+	for _, b := range breakpoints {
+		if b.kind == "exec" || !b.enabled || address < b.address || address > b.end {
+			continue
+		}
+		switch b.kind {
+		case "read", "write":
+			if b.kind != access {
+				continue
+			}
+		case "change":
+			if access != "write" || value == old {
+				continue
+			}
+		}
+		if !b.hit(machineScope{access: true, address: address, value: value, old: old}) {
+			continue
+		}
+		text := fmt.Sprintf("Watchpoint %d: %s $%04X = $%02X", b.number, access, address, value)
+		if access == "write" {
+			text += fmt.Sprintf(" (was $%02X)", old)
+		}
+		info := opcodes[memory[instructionAddress]]
+		monitorMessage("%s by $%04X %s", text, instructionAddress, formatInstruction(info, instructionAddress))
+		if watchStop == nil {
+			watchStop = b
+		}
+	}
+}
+
+// conditionHolds reports whether the condition of the breakpoint holds in scope. A condition that cannot be
+// evaluated is reported and holds, so that the emulator stops.
+func (b *breakpoint) conditionHolds(scope machineScope) bool {
+	if b.condition == "" {
+		return true
+	}
+	value, _, err := evaluateExpression(scope, b.condition)
+	if err != nil {
+		monitorMessage("%s %d: %v", b.title(), b.number, err)
+		return true
+	}
+	return value != 0
+}
+
+// title returns Breakpoint or Watchpoint
+func (b *breakpoint) title() string {
+	if b.kind == "exec" {
+		return "Breakpoint"
+	}
+	return "Watchpoint"
+}
+
+// hit counts a hit when the condition holds in scope and reports whether the breakpoint stops the emulator
+func (b *breakpoint) hit(scope machineScope) bool {
+	if !b.conditionHolds(scope) {
+		return false
+	}
+	b.hits++
+	if b.hits < b.after {
+		return false
+	}
+	if b.ignore > 0 {
+		b.ignore--
+		return false
+	}
+	return true
+}
+
+// printBreakpoints lists every breakpoint with its hit count
+func printBreakpoints() {
+	if len(breakpoints) == 0 {
+		fmt.Printf("No breakpoints\n")
+	}
+	for _, b := range breakpoints {
+		text := fmt.Sprintf("%3d  %-6s  $%04X", b.number, b.kind, b.address)
+		if b.end != b.address {
+			text += fmt.Sprintf("-$%04X", b.end)
+		}
+		if name, ok := addressName(b.address); ok {
+			text += " " + name
+		}
+		if !b.enabled {
+			text += "  disabled"
+		}
+		text += fmt.Sprintf("  hits %d", b.hits)
+		if b.after > 0 {
+			text += fmt.Sprintf("  after %d", b.after)
+		}
+		if b.ignore > 0 {
+			text += fmt.Sprintf("  ignore %d", b.ignore)
+		}
+		if b.condition != "" {
+			text += "  if " + b.condition
+		}
+		if len(b.commands) > 0 {
+			text += fmt.Sprintf("  command \"%s\"", strings.Join(b.commands, "; "))
+		}
+		fmt.Printf("%s\n", text)
+	}
+}
+
+// breakpointCommand runs the monitor commands that manage breakpoints
+func breakpointCommand(command string, args []string) error {
+	switch command {
+	case "bk", "break", "w", "watch":
+		if len(args) == 0 {
+			printBreakpoints()
+			return nil
+		}
+		b, err := addBreakpoint(command == "w" || command == "watch", args)
+		if err != nil {
+			return err
+		}
+		if b.kind == "exec" {
+			fmt.Printf("Breakpoint %d at $%04X\n", b.number, b.address)
+		} else {
+			fmt.Printf("Watchpoint %d on %s of $%04X-$%04X\n", b.number, b.kind, b.address, b.end)
+		}
+		return nil
+	case "del", "delete":
+		if len(args) == 0 {
+			breakpoints = nil
+			indexBreakpoints()
+			return nil
+		}
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("%s needs a breakpoint number", command)
+	}
+	b, err := findBreakpoint(args[0])
+	if err != nil {
+		return err
+	}
+	switch command {
+	case "del", "delete":
+		deleteBreakpoint(b)
+	case "enable", "disable":
+		b.enabled = command == "enable"
+	case "ignore":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: ignore number count")
+		}
+		if b.ignore, err = monitorCount(args[1]); err != nil {
+			return err
+		}
+	case "cond", "condition":
+		condition := strings.Join(args[1:], " ")
+		if condition != "" {
+			if _, _, err := evaluateExpression(machineScope{access: b.kind != "exec"}, condition); err != nil {
+				return err
+			}
+		}
+		b.condition = condition
+	case "command":
+		b.commands = parseCommands(args[1:])
+	}
+	indexBreakpoints()
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBreakpointCountsAreDecimal(t *testing.T) {
+	b, err := addBreakpoint(false, []string{"4000", "after", "10", "ignore", "25"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteBreakpoint(b)
+	if b.after != 10 || b.ignore != 25 {
+		t.Errorf("after 10 ignore 25 gave after %d ignore %d", b.after, b.ignore)
+	}
+	if err := breakpointCommand("ignore", []string{fmt.Sprint(b.number), "12"}); err != nil || b.ignore != 12 {
+		t.Errorf("ignore 12 gave %d, error %v", b.ignore, err)
+	}
+	for _, count := range []string{"$10", "1A", "-1"} {
+		if _, err := addBreakpoint(false, []string{"4000", "after", count}); err == nil {
+			t.Errorf("after %s was accepted", count)
+		}
+	}
+}
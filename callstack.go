@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Shadow call stack
+//
+// While the monitor is active the emulator keeps its own list of the subroutines and BRK handlers that have been
+// entered and not yet returned from, so the monitor can show how the program got to where it is without reading
+// the stack page. A JSR or BRK pushes a frame, an RTS or RTI pops the frame whose return brings the stack pointer
+// back to where it was before the call. A return that does not match the newest frame, and a PLA, PLP or TXS that
+// takes away a return address, are reported once for each instruction, as they are usually the stack tricks that
+// jump tables and error exits use.
+
+type callFrame struct {
+	opcode byte // JSR or BRK
+	caller int  // Address of the JSR or BRK
+	target int  // Address of the subroutine or BRK handler
+	sp     uint // Stack pointer before the call, which the return brings back
+	top    uint // Stack pointer after the call has pushed the return address
+}
+
+var (
+	callStack  []callFrame // Frames from the outermost call, never changed in place so the history can share them
+	callRoot   = -1        // First instruction the monitor saw, where the backtrace starts
+	callWarned = map[int]bool{}
+)
+
+// trackCall updates the call stack for the instruction at bytecounter, which is about to run
+func trackCall() {
+	if callRoot < 0 {
+		callRoot = bytecounter
+	}
+	switch opcode() {
+	case 0x20:
+		pushCall(0x20, int(operand2())<<8|int(operand1()), 2)
+	case 0x00:
+		// The BRK of this emulator moves the stack pointer by three bytes, the same as the RTI that returns
+		pushCall(0x00, int(memory[0xFFFF])<<8|int(memory[0xFFFE]), 3)
+	case 0x60:
+		returnCall("RTS", SP+2)
+	case 0x40:
+		returnCall("RTI", SP+3)
+	case 0x68, 0x28:
+		if len(callStack) > 0 && SP >= callStack[len(callStack)-1].top {
+			callWarning("%s at $%04X pulls the return address of %s", opcodes[opcode()].mnemonic, bytecounter,
+				frameName(callStack[len(callStack)-1]))
+		}
+	case 0x9A:
+		if len(callStack) > 0 && 0x100|uint(X) > callStack[len(callStack)-1].top {
+			callWarning("TXS at $%04X drops the return address of %s", bytecounter, frameName(callStack[len(callStack)-1]))
+		}
+	}
+}
+
+// pushCall adds a frame for a call to target that pushes the given number of bytes
+func pushCall(opcode byte, target int, pushed uint) {
+	frame := callFrame{opcode: opcode, caller: bytecounter, target: target, sp: SP, top: SP - pushed}
+	// A copy keeps the frames the history holds unchanged
+	callStack = append(callStack[:len(callStack):len(callStack)], frame)
+}
+
+// returnCall pops the frame a return leaves, which brings the stack pointer to after
+func returnCall(mnemonic string, after uint) {
+	if len(callStack) == 0 {
+		callWarning("%s at $%04X returns without a call", mnemonic, bytecounter)
+		return
+	}
+	newest := callStack[len(callStack)-1]
+	switch {
+	case after == newest.sp:
+		if mnemonic == "RTS" && newest.opcode == 0x20 {
+			if stacked := int(memory[0x100|(SP+1)&0xFF]) | int(memory[0x100|(SP+2)&0xFF])<<8; stacked != newest.caller {
+				callWarning("RTS at $%04X returns from %s to a changed address", bytecounter, frameName(newest))
+			}
+		}
+		callStack = callStack[:len(callStack)-1]
+	case after < newest.sp:
+		// More is on the stack than the return address, such as an address pushed to jump through with RTS
+		callWarning("%s at $%04X jumps through an address pushed in %s", mnemonic, bytecounter, frameName(newest))
+	default:
+		// The return addresses of the newest frames were pulled or dropped, the return leaves all of them
+		var dropped []string
+		for len(callStack) > 0 && callStack[len(callStack)-1].sp < after {
+			dropped = append(dropped, frameName(callStack[len(callStack)-1]))
+			callStack = callStack[:len(callStack)-1]
+		}
+		if len(callStack) > 0 && callStack[len(callStack)-1].sp == after {
+			callWarning("%s at $%04X returns from %s, skipping %s", mnemonic, bytecounter,
+				frameName(callStack[len(callStack)-1]), strings.Join(dropped, ", "))
+			callStack = callStack[:len(callStack)-1]
+		} else {
+			callWarning("%s at $%04X matches no call, leaving %s", mnemonic, bytecounter, strings.Join(dropped, ", "))
+		}
+	}
+}
+
+// callWarning reports a stack mismatch the first time the instruction at bytecounter makes it
+func callWarning(format string, args ...interface{}) {
+	if callWarned[bytecounter] {
+		return
+	}
+	callWarned[bytecounter] = true
+	monitorMessage("Call stack: "+format, args...)
+}
+
+// routineName returns the label or symbol at address, or the address in hex
+func routineName(address int) string {
+	if name, ok := addressName(address); ok {
+		return name
+	}
+	return fmt.Sprintf("$%04X", address)
+}
+
+// frameName returns the name of the subroutine or BRK handler of frame
+func frameName(frame callFrame) string {
+	if frame.opcode == 0x00 {
+		return "BRK " + routineName(frame.target)
+	}
+	return routineName(frame.target)
+}
+
+// callChain returns the routines on the call stack from the outermost, such as main → draw_screen → print_hex
+func callChain() string {
+	root := callRoot
+	if root < 0 {
+		root = bytecounter
+	}
+	names := []string{routineName(root)}
+	for _, frame := range callStack {
+		names = append(names, frameName(frame))
+	}
+	return strings.Join(names, " → ")
+}
+
+// printBacktrace prints the call chain and every frame from the newest with the call it came from
+func printBacktrace() {
+	fmt.Printf("%s\n", callChain())
+	for i := len(callStack) - 1; i >= 0; i-- {
+		frame := callStack[i]
+		from := fmt.Sprintf("$%04X", frame.caller)
+		if name := symbolicAddress(frame.caller); name != fmt.Sprintf("%04X", frame.caller) {
+			from += " " + name
+		}
+		returns := frame.caller + 3
+		if frame.opcode == 0x00 {
+			returns = frame.caller + 2
+		}
+		fmt.Printf("  #%-3d %-20s from %s, returns to $%04X\n", len(callStack)-1-i, frameName(frame), from, returns)
+	}
+}
@@ -0,0 +1,807 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Debug adapter
+//
+// The dap subcommand speaks the Debug Adapter Protocol, so editors such as VS Code can run a program in the
+// emulator and debug it. Messages are JSON with a Content-Length header, over standard input and output or over
+// a TCP connection with listen=. The adapter is the machine monitor driven by requests instead of commands: the
+// editor's breakpoints become monitor breakpoints, stepping uses the monitor's step modes, the call stack is the
+// shadow call stack and step back uses the history.
+//
+// When the launch names the source the program was assembled from, the adapter assembles it again to map every
+// source line to the address of its code, so breakpoints can be set in the source and stops are shown there.
+// Without a source the editor shows the disassembly, with the names from the symbol files.
+
+type dapMessage struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command,omitempty"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type dapResponse struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+// dapLaunch holds the arguments of the launch request, addresses are hex as on the command line
+type dapLaunch struct {
+	Program     string   `json:"program"`     // Binary to load
+	LoadAddress string   `json:"loadAddress"` // Where the binary is loaded
+	Start       string   `json:"start"`       // Where execution starts, $0000 when empty as on the command line
+	Source      string   `json:"source"`      // Source the binary was assembled from, assembled and loaded without program
+	Symbols     []string `json:"symbols"`     // Symbol files, as sym= reads them
+	StopOnEntry bool     `json:"stopOnEntry"`
+	History     *int     `json:"history"` // Instructions kept for step back
+}
+
+type dapSource struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type dapBreakpoint struct {
+	ID                   int        `json:"id,omitempty"`
+	Verified             bool       `json:"verified"`
+	Message              string     `json:"message,omitempty"`
+	Source               *dapSource `json:"source,omitempty"`
+	Line                 int        `json:"line,omitempty"`
+	InstructionReference string     `json:"instructionReference,omitempty"`
+}
+
+// dapBreakpointArguments is a breakpoint of any of the set breakpoint requests
+type dapBreakpointArguments struct {
+	Line                 int    `json:"line"`
+	Name                 string `json:"name"`
+	InstructionReference string `json:"instructionReference"`
+	Offset               int    `json:"offset"`
+	Condition            string `json:"condition"`
+	HitCondition         string `json:"hitCondition"`
+}
+
+type dapStackFrame struct {
+	ID                          int        `json:"id"`
+	Name                        string     `json:"name"`
+	Source                      *dapSource `json:"source,omitempty"`
+	Line                        int        `json:"line"`
+	Column                      int        `json:"column"`
+	InstructionPointerReference string     `json:"instructionPointerReference"`
+}
+
+type dapVariable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	VariablesReference int    `json:"variablesReference"`
+	MemoryReference    string `json:"memoryReference,omitempty"`
+}
+
+type dapInstruction struct {
+	Address          string     `json:"address"`
+	InstructionBytes string     `json:"instructionBytes,omitempty"`
+	Instruction      string     `json:"instruction"`
+	Symbol           string     `json:"symbol,omitempty"`
+	Location         *dapSource `json:"location,omitempty"`
+	Line             int        `json:"line,omitempty"`
+}
+
+const (
+	dapRegisters = 1 // Variables reference of the registers scope
+	dapFlags     = 2 // Variables reference of the flags scope
+)
+
+var (
+	dapMode       = false
+	dapWriter     io.Writer
+	dapWriting    sync.Mutex
+	dapSeq        = 0
+	dapRequests   = make(chan *dapMessage, 16) // Requests from the editor, a nil request when it has gone
+	dapConfigured = false                      // configurationDone has been received and the program runs
+	dapStopped    = false                      // The emulator waits in dapPrompt
+	dapFinished   = false                      // The program has ended and the editor has been told
+	dapLaunched   *dapLaunch
+	dapLines      = map[string]map[int]int{} // Address of the first instruction on each line of each source file
+	dapSources    = map[int]sourceLine{}     // Source line of the instruction at each address
+	dapOwned      = map[string][]*breakpoint{}
+	dapFlagBits   = []struct {
+		name string
+		bit  byte
+	}{{"N", 7}, {"V", 6}, {"B", 4}, {"D", 3}, {"I", 2}, {"Z", 1}, {"C", 0}}
+)
+
+// serveDAP waits for an editor on standard input and output, or on the TCP address of listen=, and runs the
+// program it launches
+func serveDAP(options []string) {
+	listen := ""
+	for _, option := range options {
+		if name, value, _ := strings.Cut(option, "="); name == "listen" {
+			listen = value
+		}
+	}
+	var reader io.Reader = os.Stdin
+	dapWriter = os.Stdout
+	if listen != "" {
+		listener, err := net.Listen("tcp", listen)
+		if err != nil {
+			fmt.Printf("Cannot listen on %s: %v\n", listen, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Debug adapter listening on %s\n", listener.Addr())
+		connection, err := listener.Accept()
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		listener.Close()
+		reader, dapWriter = connection, connection
+	} else {
+		// Standard output carries the protocol, anything else the emulator prints goes to standard error
+		os.Stdout = os.Stderr
+	}
+	dapMode, machineMonitor = true, true
+	go readDAP(bufio.NewReader(reader))
+	for !dapConfigured {
+		dapHandle(<-dapRequests)
+	}
+	startMonitor()
+	stepMode = ""
+	if dapLaunched.StopOnEntry {
+		stepMode = "step"
+	}
+	execute()
+	dapFinish()
+	for {
+		dapHandle(<-dapRequests)
+	}
+}
+
+// readDAP passes every request from the editor to the emulator
+func readDAP(reader *bufio.Reader) {
+	for {
+		length := -1
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				dapRequests <- nil
+				return
+			}
+			line = strings.TrimSpace(line)
+			if line == "" && length >= 0 {
+				break
+			}
+			if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+				length, _ = strconv.Atoi(strings.TrimSpace(value))
+			}
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			dapRequests <- nil
+			return
+		}
+		var message dapMessage
+		if err := json.Unmarshal(body, &message); err == nil && message.Type == "request" {
+			dapRequests <- &message
+		}
+	}
+}
+
+// dapSend writes a message to the editor
+func dapSend(message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	dapWriting.Lock()
+	defer dapWriting.Unlock()
+	fmt.Fprintf(dapWriter, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+// dapSendEvent sends an event with its body
+func dapSendEvent(event string, body interface{}) {
+	dapWriting.Lock()
+	dapSeq++
+	seq := dapSeq
+	dapWriting.Unlock()
+	dapSend(dapEvent{Seq: seq, Type: "event", Event: event, Body: body})
+}
+
+// dapOutput shows text in the debug console of the editor
+func dapOutput(text string) {
+	dapSendEvent("output", map[string]interface{}{"category": "console", "output": text + "\n"})
+}
+
+// dapPrompt tells the editor where the emulator stopped and handles requests until one resumes the program
+func dapPrompt() {
+	body := map[string]interface{}{"reason": stopReason, "threadId": 1, "allThreadsStopped": true}
+	switch {
+	case instructionCounter == 0 && stopReason == "step":
+		body["reason"] = "entry"
+	case stopReason == "exception":
+		body["description"] = fmt.Sprintf("Undefined opcode $%02X", opcode())
+	case stopBreakpoint != nil:
+		body["hitBreakpointIds"] = []int{stopBreakpoint.number}
+	}
+	dapSendEvent("stopped", body)
+	dapStopped = true
+	for !dapHandle(<-dapRequests) {
+	}
+	dapStopped = false
+	interrupted.Store(false)
+}
+
+// dapPoll is called while the program runs to handle the requests that came in, such as pause or new breakpoints
+func dapPoll() {
+	for {
+		select {
+		case request := <-dapRequests:
+			dapHandle(request)
+		default:
+			return
+		}
+	}
+}
+
+// dapFinish tells the editor that the program has ended
+func dapFinish() {
+	if dapFinished {
+		return
+	}
+	dapFinished = true
+	dapSendEvent("exited", map[string]interface{}{"exitCode": 0})
+	dapSendEvent("terminated", nil)
+}
+
+// dapHandle answers a request and reports whether the program should resume
+func dapHandle(request *dapMessage) bool {
+	if request == nil {
+		os.Exit(0)
+	}
+	var body interface{}
+	var err error
+	resume := false
+	stopped := false // The request moved the stopped emulator, which the editor has to be told
+	switch request.Command {
+	case "initialize":
+		body = map[string]interface{}{
+			"supportsConfigurationDoneRequest":  true,
+			"supportsFunctionBreakpoints":       true,
+			"supportsConditionalBreakpoints":    true,
+			"supportsHitConditionalBreakpoints": true,
+			"supportsInstructionBreakpoints":    true,
+			"supportsEvaluateForHovers":         true,
+			"supportsSetVariable":               true,
+			"supportsStepBack":                  true,
+			"supportsReadMemoryRequest":         true,
+			"supportsWriteMemoryRequest":        true,
+			"supportsDisassembleRequest":        true,
+			"supportsTerminateRequest":          true,
+		}
+	case "launch":
+		if err = dapLaunchProgram(request.Arguments); err == nil {
+			// The editor sends its breakpoints once it has the response and this event
+			defer dapSendEvent("initialized", nil)
+		}
+	case "configurationDone":
+		if dapLaunched == nil {
+			err = fmt.Errorf("the program has not been launched")
+		}
+		dapConfigured = err == nil
+	case "disconnect":
+		dapRespond(request, nil, nil)
+		os.Exit(0)
+	case "terminate":
+		// The editor disconnects once it has the terminated event
+		defer dapFinish()
+	case "threads":
+		body = map[string]interface{}{"threads": []map[string]interface{}{{"id": 1, "name": "6502"}}}
+	case "setBreakpoints", "setFunctionBreakpoints", "setInstructionBreakpoints":
+		body, err = dapSetBreakpoints(request.Command, request.Arguments)
+	case "setExceptionBreakpoints":
+		body = map[string]interface{}{"breakpoints": []dapBreakpoint{}}
+	case "stackTrace":
+		body = dapStackTrace()
+	case "scopes":
+		body = map[string]interface{}{"scopes": []map[string]interface{}{
+			{"name": "Registers", "variablesReference": dapRegisters, "expensive": false},
+			{"name": "Flags", "variablesReference": dapFlags, "expensive": false},
+		}}
+	case "variables":
+		body, err = dapVariables(request.Arguments)
+	case "setVariable":
+		body, err = dapSetVariable(request.Arguments)
+	case "evaluate":
+		body, err = dapEvaluate(request.Arguments)
+	case "readMemory":
+		body, err = dapReadMemory(request.Arguments)
+	case "writeMemory":
+		body, err = dapWriteMemory(request.Arguments)
+	case "disassemble":
+		body, err = dapDisassemble(request.Arguments)
+	case "pause":
+		interrupted.Store(true)
+	case "continue", "next", "stepIn", "stepOut":
+		if !dapStopped {
+			err = fmt.Errorf("the program is not stopped")
+			break
+		}
+		resume, stepsLeft = true, 0
+		switch request.Command {
+		case "continue":
+			stepMode = ""
+			body = map[string]interface{}{"allThreadsContinued": true}
+		case "next":
+			startStep("over", -1)
+		case "stepIn":
+			startStep("step", -1)
+		case "stepOut":
+			startStep("out", -1)
+		}
+	case "stepBack", "reverseContinue":
+		switch {
+		case !dapStopped:
+			err = fmt.Errorf("the program is not stopped")
+		case !recordHistory:
+			err = fmt.Errorf("the history is off, launch with a history length above 0")
+		case request.Command == "stepBack":
+			err, stopped = reverseStep(1), true
+		default:
+			err, stopped = reverseContinue(), true
+		}
+	default:
+		err = fmt.Errorf("%s is not supported", request.Command)
+	}
+	dapRespond(request, body, err)
+	if stopped {
+		stopReason, stopBreakpoint = "step", nil
+		dapSendEvent("stopped", map[string]interface{}{"reason": "step", "threadId": 1, "allThreadsStopped": true})
+	}
+	return resume
+}
+
+// dapRespond sends the response to a request, which failed when err is set
+func dapRespond(request *dapMessage, body interface{}, err error) {
+	dapWriting.Lock()
+	dapSeq++
+	response := dapResponse{Seq: dapSeq, Type: "response", RequestSeq: request.Seq, Success: err == nil,
+		Command: request.Command, Body: body}
+	dapWriting.Unlock()
+	if err != nil {
+		response.Message = err.Error()
+	}
+	dapSend(response)
+}
+
+// dapAddress parses a hex address written as 4000, $4000 or 0x4000
+func dapAddress(text string) (int, error) {
+	text = strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(text, "$"), "0x"), "0X")
+	value, err := strconv.ParseUint(text, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a hex address", text)
+	}
+	return int(value), nil
+}
+
+// dapLaunchProgram loads the program, its symbols and its source as the command line does for mon
+func dapLaunchProgram(arguments json.RawMessage) error {
+	launch := &dapLaunch{}
+	if err := json.Unmarshal(arguments, launch); err != nil {
+		return err
+	}
+	if launch.Program == "" && launch.Source == "" {
+		return fmt.Errorf("the launch needs a program or a source")
+	}
+	if launch.History != nil {
+		historyLimit = *launch.History
+	}
+	for _, filename := range launch.Symbols {
+		if _, err := loadSymbols(filename); err != nil {
+			return fmt.Errorf("cannot read symbols: %v", err)
+		}
+	}
+	var a *assembler
+	if launch.Source != "" {
+		var err error
+		if a, err = assemble(launch.Source, true); err != nil {
+			return err
+		}
+		for name, value := range a.symbols {
+			addSymbol(name, value)
+		}
+		mapSource(a)
+	}
+	if launch.Program != "" {
+		var err error
+		if launch.LoadAddress != "" {
+			if loadAddress, err = dapAddress(launch.LoadAddress); err != nil {
+				return err
+			}
+		}
+		if file, err = os.ReadFile(launch.Program); err != nil {
+			return err
+		}
+		copy(memory[loadAddress:], file)
+		if a != nil {
+			for address := a.low; address <= a.high; address++ {
+				if memory[address] != a.code[address] && dapSources[address].file != "" {
+					dapOutput(fmt.Sprintf("%s does not match %s at $%04X, it may need to be assembled again",
+						launch.Program, launch.Source, address))
+					break
+				}
+			}
+		}
+	} else {
+		loadAddress, file = a.low, a.code[a.low:a.high+1]
+		copy(memory[loadAddress:], file)
+	}
+	startAddress, endAddress = loadAddress, fileEnd()
+	collectLabels(loadAddress, linearStarts(startAddress, endAddress))
+	reset()
+	if launch.Start != "" {
+		start, err := dapAddress(launch.Start)
+		if err != nil {
+			return err
+		}
+		PC, bytecounter = start, start
+	}
+	dapLaunched = launch
+	return nil
+}
+
+// mapSource records the address of the code on every line of the listing the assembler kept
+func mapSource(a *assembler) {
+	for _, l := range a.listed {
+		if l.address < 0 || l.cycles == 0 {
+			continue
+		}
+		path, err := filepath.Abs(l.line.file)
+		if err != nil {
+			continue
+		}
+		if dapLines[path] == nil {
+			dapLines[path] = map[int]int{}
+		}
+		if _, ok := dapLines[path][l.line.number]; !ok {
+			dapLines[path][l.line.number] = l.address
+		}
+		if _, ok := dapSources[l.address]; !ok {
+			dapSources[l.address] = sourceLine{file: path, number: l.line.number}
+		}
+	}
+}
+
+// sourceOf returns the source file and line of the code at address, nil when there is none
+func sourceOf(address int) (*dapSource, int) {
+	line, ok := dapSources[address]
+	if !ok {
+		return nil, 0
+	}
+	return &dapSource{Name: filepath.Base(line.file), Path: line.file}, line.number
+}
+
+// dapSetBreakpoints replaces the breakpoints of a source file, or the function or instruction breakpoints
+func dapSetBreakpoints(command string, arguments json.RawMessage) (interface{}, error) {
+	var args struct {
+		Source      dapSource                `json:"source"`
+		Breakpoints []dapBreakpointArguments `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	owner := command
+	if command == "setBreakpoints" {
+		path, err := filepath.Abs(args.Source.Path)
+		if err != nil {
+			return nil, err
+		}
+		args.Source, owner = dapSource{Name: filepath.Base(path), Path: path}, "source "+path
+	}
+	for _, b := range dapOwned[owner] {
+		deleteBreakpoint(b)
+	}
+	dapOwned[owner] = nil
+	result := []dapBreakpoint{}
+	for _, requested := range args.Breakpoints {
+		var address int
+		var err error
+		line := 0
+		switch command {
+		case "setBreakpoints":
+			address, line, err = sourceAddress(args.Source.Path, requested.Line)
+		case "setFunctionBreakpoints":
+			address, err = monitorValue(requested.Name)
+		default:
+			address, err = dapAddress(requested.InstructionReference)
+			address = (address + requested.Offset) & 0xFFFF
+		}
+		if err == nil {
+			var b *breakpoint
+			if b, err = addBreakpoint(false, breakpointArguments(address, requested)); err == nil {
+				dapOwned[owner] = append(dapOwned[owner], b)
+				shown := dapBreakpoint{ID: b.number, Verified: true, InstructionReference: fmt.Sprintf("0x%04X", address)}
+				if line > 0 {
+					shown.Source, shown.Line = &args.Source, line
+				}
+				result = append(result, shown)
+				continue
+			}
+		}
+		result = append(result, dapBreakpoint{Verified: false, Message: err.Error(), Line: requested.Line})
+	}
+	return map[string]interface{}{"breakpoints": result}, nil
+}
+
+// sourceAddress returns the address of the code on line of the source file, or on the next line that has code
+func sourceAddress(path string, line int) (int, int, error) {
+	lines := dapLines[path]
+	if lines == nil {
+		return 0, 0, fmt.Errorf("%s is not the source of the program", filepath.Base(path))
+	}
+	best := -1
+	for number := range lines {
+		if number >= line && (best < 0 || number < best) {
+			best = number
+		}
+	}
+	if best < 0 {
+		return 0, 0, fmt.Errorf("no code from line %d on", line)
+	}
+	return lines[best], best, nil
+}
+
+// breakpointArguments returns the monitor arguments of a breakpoint at address with the condition and hit count
+// the editor gave. A hit count of n or >=n stops from the nth hit on.
+func breakpointArguments(address int, requested dapBreakpointArguments) []string {
+	args := []string{fmt.Sprintf("$%04X", address)}
+	if hits := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(requested.HitCondition), ">=")); hits != "" {
+		args = append(args, "after", hits)
+	}
+	if requested.Condition != "" {
+		args = append(args, "if", requested.Condition)
+	}
+	return args
+}
+
+// dapStackTrace returns a frame for the current instruction and one for every call on the shadow call stack,
+// newest first
+func dapStackTrace() interface{} {
+	root := callRoot
+	if root < 0 {
+		root = bytecounter
+	}
+	frames := []dapStackFrame{}
+	address := bytecounter
+	for i := len(callStack); i >= 0; i-- {
+		name := routineName(root)
+		if i > 0 {
+			name = frameName(callStack[i-1])
+		}
+		frame := dapStackFrame{ID: len(frames), Name: name, InstructionPointerReference: fmt.Sprintf("0x%04X", address)}
+		if frame.Source, frame.Line = sourceOf(address); frame.Source != nil {
+			frame.Column = 1
+		}
+		frames = append(frames, frame)
+		if i > 0 {
+			address = callStack[i-1].caller
+		}
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}
+}
+
+// dapVariables returns the registers or the flags
+func dapVariables(arguments json.RawMessage) (interface{}, error) {
+	var args struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	variables := []dapVariable{}
+	switch args.VariablesReference {
+	case dapRegisters:
+		variables = append(variables,
+			dapVariable{Name: "PC", Value: fmt.Sprintf("$%04X", bytecounter), MemoryReference: fmt.Sprintf("0x%04X", bytecounter)},
+			dapVariable{Name: "A", Value: fmt.Sprintf("$%02X", A)},
+			dapVariable{Name: "X", Value: fmt.Sprintf("$%02X", X)},
+			dapVariable{Name: "Y", Value: fmt.Sprintf("$%02X", Y)},
+			dapVariable{Name: "SP", Value: fmt.Sprintf("$%02X", byte(SP)), MemoryReference: fmt.Sprintf("0x%04X", 0x100|SP&0xFF)},
+			dapVariable{Name: "SR", Value: fmt.Sprintf("%08b", SR)},
+			dapVariable{Name: "cycles", Value: fmt.Sprint(cycleCount)},
+			dapVariable{Name: "instructions", Value: fmt.Sprint(instructionCounter)})
+	case dapFlags:
+		for _, flag := range dapFlagBits {
+			variables = append(variables, dapVariable{Name: flag.name, Value: fmt.Sprint(getSRBit(flag.bit))})
+		}
+	}
+	return map[string]interface{}{"variables": variables}, nil
+}
+
+// dapSetVariable changes a register or a flag
+func dapSetVariable(arguments json.RawMessage) (interface{}, error) {
+	var args struct {
+		VariablesReference int    `json:"variablesReference"`
+		Name               string `json:"name"`
+		Value              string `json:"value"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	value := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(args.Value), "0x"), "0X")
+	if args.VariablesReference == dapFlags {
+		for _, flag := range dapFlagBits {
+			if flag.name != args.Name {
+				continue
+			}
+			switch value {
+			case "0":
+				SR &^= 1 << flag.bit
+			case "1":
+				setSRBitOn(flag.bit)
+			default:
+				return nil, fmt.Errorf("a flag is 0 or 1")
+			}
+			return map[string]interface{}{"value": value}, nil
+		}
+		return nil, fmt.Errorf("unknown flag %s", args.Name)
+	}
+	if err := setRegisters([]string{args.Name + "=" + value}); err != nil {
+		return nil, err
+	}
+	variables, _ := dapVariables(json.RawMessage(`{"variablesReference":1}`))
+	for _, v := range variables.(map[string]interface{})["variables"].([]dapVariable) {
+		if strings.EqualFold(v.Name, args.Name) {
+			return map[string]interface{}{"value": v.Value}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown register %s", args.Name)
+}
+
+// dapEvaluate evaluates an expression with the names of breakpoint conditions, for the watch and hover views
+func dapEvaluate(arguments json.RawMessage) (interface{}, error) {
+	var args struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	value, _, err := evaluateExpression(machineScope{}, args.Expression)
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{"result": fmt.Sprintf("$%02X (%d)", value, value), "variablesReference": 0}
+	if value >= 0 && value <= 0xFFFF {
+		body["memoryReference"] = fmt.Sprintf("0x%04X", value)
+	}
+	return body, nil
+}
+
+// dapReadMemory returns memory from an address as base64
+func dapReadMemory(arguments json.RawMessage) (interface{}, error) {
+	var args struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Count           int    `json:"count"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	address, err := dapAddress(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+	address += args.Offset
+	start, end := address, address+args.Count
+	if start < 0 {
+		start = 0
+	}
+	if end > len(memory) {
+		end = len(memory)
+	}
+	data := []byte{}
+	if start < end {
+		data = memory[start:end]
+	}
+	return map[string]interface{}{"address": fmt.Sprintf("0x%04X", start), "data": base64.StdEncoding.EncodeToString(data),
+		"unreadableBytes": args.Count - len(data)}, nil
+}
+
+// dapWriteMemory writes base64 data to memory
+func dapWriteMemory(arguments json.RawMessage) (interface{}, error) {
+	var args struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Data            string `json:"data"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	address, err := dapAddress(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(args.Data)
+	if err != nil {
+		return nil, err
+	}
+	address += args.Offset
+	written := 0
+	for i, value := range data {
+		if address+i >= 0 && address+i < len(memory) {
+			memory[address+i] = value
+			written++
+		}
+	}
+	return map[string]interface{}{"bytesWritten": written}, nil
+}
+
+// dapDisassemble returns instructions from an address, which may start a number of instructions before it
+func dapDisassemble(arguments json.RawMessage) (interface{}, error) {
+	var args struct {
+		MemoryReference   string `json:"memoryReference"`
+		Offset            int    `json:"offset"`
+		InstructionOffset int    `json:"instructionOffset"`
+		InstructionCount  int    `json:"instructionCount"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	address, err := dapAddress(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+	address += args.Offset
+	instructions := []dapInstruction{}
+	if args.InstructionOffset < 0 {
+		// Code cannot be decoded backwards, so addresses before the start of memory are given as padding
+		before := disassemblyBefore(address, -args.InstructionOffset)
+		count := 0
+		for at := before; at < address; at += instructionLength(at) {
+			count++
+		}
+		for i := count; i < -args.InstructionOffset; i++ {
+			instructions = append(instructions, dapInstruction{Address: fmt.Sprintf("0x%04X", 0), Instruction: "??"})
+		}
+		address = before
+	}
+	for i := 0; i < args.InstructionOffset; i++ {
+		address += instructionLength(address)
+	}
+	for len(instructions) < args.InstructionCount {
+		if address > 0xFFFF {
+			instructions = append(instructions, dapInstruction{Address: fmt.Sprintf("0x%04X", address), Instruction: "??"})
+			address++
+			continue
+		}
+		bytes, text, length := instructionText(address)
+		instruction := dapInstruction{Address: fmt.Sprintf("0x%04X", address), InstructionBytes: bytes, Instruction: text}
+		instruction.Symbol, _ = addressName(address)
+		instruction.Location, instruction.Line = sourceOf(address)
+		instructions = append(instructions, instruction)
+		address += length
+	}
+	return map[string]interface{}{"instructions": instructions}, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// dapFrame encodes a message with its Content-Length header, as an editor sends it
+func dapFrame(header string, message string) string {
+	return fmt.Sprintf("%s: %d\r\n\r\n%s", header, len(message), message)
+}
+
+// dapExchange passes the framed requests through readDAP and dapHandle and returns the messages written
+func dapExchange(t *testing.T, requests string) []interface{} {
+	t.Helper()
+	var written bytes.Buffer
+	saved := dapWriter
+	dapWriter, dapSeq = &written, 0
+	defer func() {
+		dapWriter = saved
+	}()
+	go readDAP(bufio.NewReader(strings.NewReader(requests)))
+	for request := <-dapRequests; request != nil; request = <-dapRequests {
+		dapHandle(request)
+	}
+	var messages []interface{}
+	reader := bufio.NewReader(&written)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return messages
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Content-Length:")))
+		if err != nil {
+			t.Fatalf("bad header %q", line)
+		}
+		if line, _ = reader.ReadString('\n'); line != "\r\n" {
+			t.Fatalf("the header ends with %q", line)
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			t.Fatal(err)
+		}
+		var message interface{}
+		if err := json.Unmarshal(body, &message); err != nil {
+			t.Fatalf("%v in %s", err, body)
+		}
+		messages = append(messages, message)
+	}
+}
+
+func TestDAPRequests(t *testing.T) {
+	reset := func() {
+		breakpoints, nextBreakpoint, dapOwned = nil, 1, map[string][]*breakpoint{}
+		indexBreakpoints()
+	}
+	reset()
+	defer reset()
+	memory, labels, symbols = [65536]byte{}, map[int]string{}, map[int]string{}
+	A, X, Y, SP, SR, bytecounter, cycleCount, instructionCounter = 0x01, 0x02, 0x03, 0x01FD, 0x24, 0x1000, 0, 0
+
+	tests := []struct {
+		request  string
+		response string
+	}{
+		{`{"seq":1,"type":"request","command":"writeMemory","arguments":{"memoryReference":"0x1000","data":"qrvM"}}`,
+			`{"seq":1,"type":"response","request_seq":1,"success":true,"command":"writeMemory","body":{"bytesWritten":3}}`},
+		{`{"seq":2,"type":"request","command":"readMemory","arguments":{"memoryReference":"0x1000","offset":1,"count":2}}`,
+			`{"seq":2,"type":"response","request_seq":2,"success":true,"command":"readMemory",` +
+				`"body":{"address":"0x1001","data":"u8w=","unreadableBytes":0}}`},
+		{`{"seq":3,"type":"request","command":"setInstructionBreakpoints",` +
+			`"arguments":{"breakpoints":[{"instructionReference":"0x1000","offset":2,"hitCondition":"10"}]}}`,
+			`{"seq":3,"type":"response","request_seq":3,"success":true,"command":"setInstructionBreakpoints",` +
+				`"body":{"breakpoints":[{"id":1,"verified":true,"instructionReference":"0x1002"}]}}`},
+		{`{"seq":4,"type":"request","command":"setVariable","arguments":{"variablesReference":1,"name":"A","value":"42"}}`,
+			`{"seq":4,"type":"response","request_seq":4,"success":true,"command":"setVariable","body":{"value":"$42"}}`},
+		{`{"seq":5,"type":"request","command":"variables","arguments":{"variablesReference":1}}`,
+			`{"seq":5,"type":"response","request_seq":5,"success":true,"command":"variables","body":{"variables":[` +
+				`{"name":"PC","value":"$1000","variablesReference":0,"memoryReference":"0x1000"},` +
+				`{"name":"A","value":"$42","variablesReference":0},` +
+				`{"name":"X","value":"$02","variablesReference":0},` +
+				`{"name":"Y","value":"$03","variablesReference":0},` +
+				`{"name":"SP","value":"$FD","variablesReference":0,"memoryReference":"0x01FD"},` +
+				`{"name":"SR","value":"00100100","variablesReference":0},` +
+				`{"name":"cycles","value":"0","variablesReference":0},` +
+				`{"name":"instructions","value":"0","variablesReference":0}]}}`},
+		{`{"seq":6,"type":"request","command":"frobnicate"}`,
+			`{"seq":6,"type":"response","request_seq":6,"success":false,"command":"frobnicate",` +
+				`"message":"frobnicate is not supported"}`},
+	}
+	var requests string
+	for i, test := range tests {
+		// Header names are not case sensitive and other headers are passed over
+		header := "Content-Length"
+		if i%2 == 1 {
+			header = "X-Other: 1\r\ncontent-length"
+		}
+		requests += dapFrame(header, test.request)
+	}
+	messages := dapExchange(t, requests)
+	if len(messages) != len(tests) {
+		t.Fatalf("got %d messages %v, want %d", len(messages), messages, len(tests))
+	}
+	for i, test := range tests {
+		var want interface{}
+		if err := json.Unmarshal([]byte(test.response), &want); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(messages[i], want) {
+			got, _ := json.Marshal(messages[i])
+			t.Errorf("%s\ngot  %s\nwant %s", test.request, got, test.response)
+		}
+	}
+	if !bytes.Equal(memory[0x1000:0x1003], []byte{0xAA, 0xBB, 0xCC}) {
+		t.Errorf("memory is % X after writing AA BB CC", memory[0x1000:0x1003])
+	}
+	if len(breakpoints) != 1 || breakpoints[0].address != 0x1002 || breakpoints[0].after != 10 {
+		t.Errorf("the instruction breakpoint became %d breakpoints", len(breakpoints))
+	}
+}
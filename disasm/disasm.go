@@ -0,0 +1,325 @@
+// Package disasm decodes NMOS 6502 machine code into structured instructions.
+//
+// It is the decoder behind the six5go2 static disassemblers, exposed so that other Go tools can work with
+// decoded instructions directly instead of parsing the disassembler's text output.
+package disasm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Mode is a 6502 addressing mode
+type Mode string
+
+const (
+	Implied     Mode = "implied"
+	Accumulator Mode = "accumulator"
+	Immediate   Mode = "immediate"
+	ZeroPage    Mode = "zeropage"
+	ZeroPageX   Mode = "zeropagex"
+	ZeroPageY   Mode = "zeropagey"
+	Absolute    Mode = "absolute"
+	AbsoluteX   Mode = "absolutex"
+	AbsoluteY   Mode = "absolutey"
+	Indirect    Mode = "indirect"
+	IndirectX   Mode = "indirectx"
+	IndirectY   Mode = "indirecty"
+	Relative    Mode = "relative"
+)
+
+// Opcode describes one opcode of the NMOS 6502
+type Opcode struct {
+	Mnemonic        string // Three letter mnemonic, empty for undefined opcodes
+	Mode            Mode   // Addressing mode
+	Length          int    // Instruction length in bytes including the opcode
+	Cycles          int    // Base cycle count
+	PageCrossCycles int    // Extra cycles when indexing or a taken branch crosses a page boundary
+}
+
+// Opcodes maps every documented NMOS 6502 opcode to its mnemonic, addressing mode, length and timing.
+// Undefined opcodes are left as the zero value.
+var Opcodes = [256]Opcode{
+	// ADC - Add Memory to Accumulator with Carry
+	0x69: {"ADC", Immediate, 2, 2, 0},
+	0x65: {"ADC", ZeroPage, 2, 3, 0},
+	0x75: {"ADC", ZeroPageX, 2, 4, 0},
+	0x6D: {"ADC", Absolute, 3, 4, 0},
+	0x7D: {"ADC", AbsoluteX, 3, 4, 1},
+	0x79: {"ADC", AbsoluteY, 3, 4, 1},
+	0x61: {"ADC", IndirectX, 2, 6, 0},
+	0x71: {"ADC", IndirectY, 2, 5, 1},
+	// AND - "AND" Memory with Accumulator
+	0x29: {"AND", Immediate, 2, 2, 0},
+	0x25: {"AND", ZeroPage, 2, 3, 0},
+	0x35: {"AND", ZeroPageX, 2, 4, 0},
+	0x2D: {"AND", Absolute, 3, 4, 0},
+	0x3D: {"AND", AbsoluteX, 3, 4, 1},
+	0x39: {"AND", AbsoluteY, 3, 4, 1},
+	0x21: {"AND", IndirectX, 2, 6, 0},
+	0x31: {"AND", IndirectY, 2, 5, 1},
+	// ASL - Arithmetic Shift Left
+	0x0A: {"ASL", Accumulator, 1, 2, 0},
+	0x06: {"ASL", ZeroPage, 2, 5, 0},
+	0x16: {"ASL", ZeroPageX, 2, 6, 0},
+	0x0E: {"ASL", Absolute, 3, 6, 0},
+	0x1E: {"ASL", AbsoluteX, 3, 7, 0},
+	// Branches
+	0x90: {"BCC", Relative, 2, 2, 1},
+	0xB0: {"BCS", Relative, 2, 2, 1},
+	0xF0: {"BEQ", Relative, 2, 2, 1},
+	0x30: {"BMI", Relative, 2, 2, 1},
+	0xD0: {"BNE", Relative, 2, 2, 1},
+	0x10: {"BPL", Relative, 2, 2, 1},
+	0x50: {"BVC", Relative, 2, 2, 1},
+	0x70: {"BVS", Relative, 2, 2, 1},
+	// BIT - Test Bits in Memory with Accumulator
+	0x24: {"BIT", ZeroPage, 2, 3, 0},
+	0x2C: {"BIT", Absolute, 3, 4, 0},
+	// BRK - Break Command
+	0x00: {"BRK", Implied, 1, 7, 0},
+	// Flag instructions
+	0x18: {"CLC", Implied, 1, 2, 0},
+	0xD8: {"CLD", Implied, 1, 2, 0},
+	0x58: {"CLI", Implied, 1, 2, 0},
+	0xB8: {"CLV", Implied, 1, 2, 0},
+	0x38: {"SEC", Implied, 1, 2, 0},
+	0xF8: {"SED", Implied, 1, 2, 0},
+	0x78: {"SEI", Implied, 1, 2, 0},
+	// CMP - Compare Memory and Accumulator
+	0xC9: {"CMP", Immediate, 2, 2, 0},
+	0xC5: {"CMP", ZeroPage, 2, 3, 0},
+	0xD5: {"CMP", ZeroPageX, 2, 4, 0},
+	0xCD: {"CMP", Absolute, 3, 4, 0},
+	0xDD: {"CMP", AbsoluteX, 3, 4, 1},
+	0xD9: {"CMP", AbsoluteY, 3, 4, 1},
+	0xC1: {"CMP", IndirectX, 2, 6, 0},
+	0xD1: {"CMP", IndirectY, 2, 5, 1},
+	// CPX - Compare Index Register X To Memory
+	0xE0: {"CPX", Immediate, 2, 2, 0},
+	0xE4: {"CPX", ZeroPage, 2, 3, 0},
+	0xEC: {"CPX", Absolute, 3, 4, 0},
+	// CPY - Compare Index Register Y To Memory
+	0xC0: {"CPY", Immediate, 2, 2, 0},
+	0xC4: {"CPY", ZeroPage, 2, 3, 0},
+	0xCC: {"CPY", Absolute, 3, 4, 0},
+	// DEC - Decrement Memory By One
+	0xC6: {"DEC", ZeroPage, 2, 5, 0},
+	0xD6: {"DEC", ZeroPageX, 2, 6, 0},
+	0xCE: {"DEC", Absolute, 3, 6, 0},
+	0xDE: {"DEC", AbsoluteX, 3, 7, 0},
+	// Register increments and decrements
+	0xCA: {"DEX", Implied, 1, 2, 0},
+	0x88: {"DEY", Implied, 1, 2, 0},
+	0xE8: {"INX", Implied, 1, 2, 0},
+	0xC8: {"INY", Implied, 1, 2, 0},
+	// EOR - "Exclusive OR" Memory with Accumulator
+	0x49: {"EOR", Immediate, 2, 2, 0},
+	0x45: {"EOR", ZeroPage, 2, 3, 0},
+	0x55: {"EOR", ZeroPageX, 2, 4, 0},
+	0x4D: {"EOR", Absolute, 3, 4, 0},
+	0x5D: {"EOR", AbsoluteX, 3, 4, 1},
+	0x59: {"EOR", AbsoluteY, 3, 4, 1},
+	0x41: {"EOR", IndirectX, 2, 6, 0},
+	0x51: {"EOR", IndirectY, 2, 5, 1},
+	// INC - Increment Memory By One
+	0xE6: {"INC", ZeroPage, 2, 5, 0},
+	0xF6: {"INC", ZeroPageX, 2, 6, 0},
+	0xEE: {"INC", Absolute, 3, 6, 0},
+	0xFE: {"INC", AbsoluteX, 3, 7, 0},
+	// JMP - Jump
+	0x4C: {"JMP", Absolute, 3, 3, 0},
+	0x6C: {"JMP", Indirect, 3, 5, 0},
+	// JSR - Jump To Subroutine
+	0x20: {"JSR", Absolute, 3, 6, 0},
+	// LDA - Load Accumulator with Memory
+	0xA9: {"LDA", Immediate, 2, 2, 0},
+	0xA5: {"LDA", ZeroPage, 2, 3, 0},
+	0xB5: {"LDA", ZeroPageX, 2, 4, 0},
+	0xAD: {"LDA", Absolute, 3, 4, 0},
+	0xBD: {"LDA", AbsoluteX, 3, 4, 1},
+	0xB9: {"LDA", AbsoluteY, 3, 4, 1},
+	0xA1: {"LDA", IndirectX, 2, 6, 0},
+	0xB1: {"LDA", IndirectY, 2, 5, 1},
+	// LDX - Load Index Register X From Memory
+	0xA2: {"LDX", Immediate, 2, 2, 0},
+	0xA6: {"LDX", ZeroPage, 2, 3, 0},
+	0xB6: {"LDX", ZeroPageY, 2, 4, 0},
+	0xAE: {"LDX", Absolute, 3, 4, 0},
+	0xBE: {"LDX", AbsoluteY, 3, 4, 1},
+	// LDY - Load Index Register Y From Memory
+	0xA0: {"LDY", Immediate, 2, 2, 0},
+	0xA4: {"LDY", ZeroPage, 2, 3, 0},
+	0xB4: {"LDY", ZeroPageX, 2, 4, 0},
+	0xAC: {"LDY", Absolute, 3, 4, 0},
+	0xBC: {"LDY", AbsoluteX, 3, 4, 1},
+	// LSR - Logical Shift Right
+	0x4A: {"LSR", Accumulator, 1, 2, 0},
+	0x46: {"LSR", ZeroPage, 2, 5, 0},
+	0x56: {"LSR", ZeroPageX, 2, 6, 0},
+	0x4E: {"LSR", Absolute, 3, 6, 0},
+	0x5E: {"LSR", AbsoluteX, 3, 7, 0},
+	// NOP - No Operation
+	0xEA: {"NOP", Implied, 1, 2, 0},
+	// ORA - "OR" Memory with Accumulator
+	0x09: {"ORA", Immediate, 2, 2, 0},
+	0x05: {"ORA", ZeroPage, 2, 3, 0},
+	0x15: {"ORA", ZeroPageX, 2, 4, 0},
+	0x0D: {"ORA", Absolute, 3, 4, 0},
+	0x1D: {"ORA", AbsoluteX, 3, 4, 1},
+	0x19: {"ORA", AbsoluteY, 3, 4, 1},
+	0x01: {"ORA", IndirectX, 2, 6, 0},
+	0x11: {"ORA", IndirectY, 2, 5, 1},
+	// Stack instructions
+	0x48: {"PHA", Implied, 1, 3, 0},
+	0x08: {"PHP", Implied, 1, 3, 0},
+	0x68: {"PLA", Implied, 1, 4, 0},
+	0x28: {"PLP", Implied, 1, 4, 0},
+	// ROL - Rotate Left
+	0x2A: {"ROL", Accumulator, 1, 2, 0},
+	0x26: {"ROL", ZeroPage, 2, 5, 0},
+	0x36: {"ROL", ZeroPageX, 2, 6, 0},
+	0x2E: {"ROL", Absolute, 3, 6, 0},
+	0x3E: {"ROL", AbsoluteX, 3, 7, 0},
+	// ROR - Rotate Right
+	0x6A: {"ROR", Accumulator, 1, 2, 0},
+	0x66: {"ROR", ZeroPage, 2, 5, 0},
+	0x76: {"ROR", ZeroPageX, 2, 6, 0},
+	0x6E: {"ROR", Absolute, 3, 6, 0},
+	0x7E: {"ROR", AbsoluteX, 3, 7, 0},
+	// Returns
+	0x40: {"RTI", Implied, 1, 6, 0},
+	0x60: {"RTS", Implied, 1, 6, 0},
+	// SBC - Subtract Memory from Accumulator with Borrow
+	0xE9: {"SBC", Immediate, 2, 2, 0},
+	0xE5: {"SBC", ZeroPage, 2, 3, 0},
+	0xF5: {"SBC", ZeroPageX, 2, 4, 0},
+	0xED: {"SBC", Absolute, 3, 4, 0},
+	0xFD: {"SBC", AbsoluteX, 3, 4, 1},
+	0xF9: {"SBC", AbsoluteY, 3, 4, 1},
+	0xE1: {"SBC", IndirectX, 2, 6, 0},
+	0xF1: {"SBC", IndirectY, 2, 5, 1},
+	// STA - Store Accumulator in Memory
+	0x85: {"STA", ZeroPage, 2, 3, 0},
+	0x95: {"STA", ZeroPageX, 2, 4, 0},
+	0x8D: {"STA", Absolute, 3, 4, 0},
+	0x9D: {"STA", AbsoluteX, 3, 5, 0},
+	0x99: {"STA", AbsoluteY, 3, 5, 0},
+	0x81: {"STA", IndirectX, 2, 6, 0},
+	0x91: {"STA", IndirectY, 2, 6, 0},
+	// STX - Store Index Register X In Memory
+	0x86: {"STX", ZeroPage, 2, 3, 0},
+	0x96: {"STX", ZeroPageY, 2, 4, 0},
+	0x8E: {"STX", Absolute, 3, 4, 0},
+	// STY - Store Index Register Y In Memory
+	0x84: {"STY", ZeroPage, 2, 3, 0},
+	0x94: {"STY", ZeroPageX, 2, 4, 0},
+	0x8C: {"STY", Absolute, 3, 4, 0},
+	// Register transfers
+	0xAA: {"TAX", Implied, 1, 2, 0},
+	0xA8: {"TAY", Implied, 1, 2, 0},
+	0xBA: {"TSX", Implied, 1, 2, 0},
+	0x8A: {"TXA", Implied, 1, 2, 0},
+	0x9A: {"TXS", Implied, 1, 2, 0},
+	0x98: {"TYA", Implied, 1, 2, 0},
+}
+
+var (
+	// ErrUndefined is returned for opcodes that are not part of the documented 6502 instruction set
+	ErrUndefined = errors.New("undefined opcode")
+	// ErrTruncated is returned when an instruction runs past the end of memory
+	ErrTruncated = errors.New("instruction runs past the end of memory")
+)
+
+// Instruction is a single decoded instruction
+type Instruction struct {
+	Address           uint16 `json:"address"`
+	Opcode            byte   `json:"opcode"`
+	Mnemonic          string `json:"mnemonic"`
+	Mode              Mode   `json:"mode"`
+	Bytes             []int  `json:"bytes"`             // The opcode and operand bytes
+	Length            int    `json:"length"`            // Length in bytes
+	Operand           uint16 `json:"operand"`           // Operand value, 8 or 16 bits depending on the mode
+	Target            uint16 `json:"target"`            // Effective target, see TargetKnown
+	TargetKnown       bool   `json:"targetKnown"`       // Whether the instruction refers to an address
+	Cycles            int    `json:"cycles"`            // Base cycle count
+	PageCrossCycles   int    `json:"pageCrossCycles"`   // Extra cycles when a page boundary is crossed
+	BranchTakenCycles int    `json:"branchTakenCycles"` // Extra cycles when a branch is taken
+	Text              string `json:"text"`              // Standard 6502 assembler notation
+}
+
+// Decode decodes the instruction at addr in mem, where mem holds the 6502 address space from address zero.
+//
+// The target of an instruction is the address it refers to before any indexing: the operand of zero page and
+// absolute modes, the zero page pointer of the (indirect,X) and (indirect),Y modes and the destination of a
+// branch. For JMP (indirect) it is the address read from the pointer when the pointer lies inside mem.
+func Decode(mem []byte, addr uint16) (Instruction, error) {
+	if int(addr) >= len(mem) {
+		return Instruction{}, ErrTruncated
+	}
+	op := Opcodes[mem[addr]]
+	in := Instruction{Address: addr, Opcode: mem[addr], Mnemonic: op.Mnemonic, Mode: op.Mode, Length: 1}
+	if op.Mnemonic == "" {
+		in.Bytes = []int{int(mem[addr])}
+		return in, ErrUndefined
+	}
+	if int(addr)+op.Length > len(mem) {
+		return in, ErrTruncated
+	}
+	in.Length = op.Length
+	in.Cycles = op.Cycles
+	in.PageCrossCycles = op.PageCrossCycles
+	for i := 0; i < op.Length; i++ {
+		in.Bytes = append(in.Bytes, int(mem[int(addr)+i]))
+	}
+	switch op.Length {
+	case 2:
+		in.Operand = uint16(mem[addr+1])
+	case 3:
+		in.Operand = uint16(mem[addr+2])<<8 | uint16(mem[addr+1])
+	}
+	switch op.Mode {
+	case ZeroPage, ZeroPageX, ZeroPageY, IndirectX, IndirectY, Absolute, AbsoluteX, AbsoluteY:
+		in.Target, in.TargetKnown = in.Operand, true
+	case Relative:
+		in.Target, in.TargetKnown = addr+2+uint16(int8(in.Operand)), true
+		in.BranchTakenCycles = 1
+	case Indirect:
+		// The NMOS 6502 never carries into the high byte of the pointer
+		high := in.Operand&0xFF00 | (in.Operand+1)&0x00FF
+		if int(in.Operand) < len(mem) && int(high) < len(mem) {
+			in.Target, in.TargetKnown = uint16(mem[high])<<8|uint16(mem[in.Operand]), true
+		}
+	}
+	in.Text = Format(in)
+	return in, nil
+}
+
+// Format returns a decoded instruction in standard 6502 assembler notation
+func Format(in Instruction) string {
+	switch in.Mode {
+	case Immediate:
+		return fmt.Sprintf("%s #$%02X", in.Mnemonic, in.Operand)
+	case ZeroPage:
+		return fmt.Sprintf("%s $%02X", in.Mnemonic, in.Operand)
+	case ZeroPageX:
+		return fmt.Sprintf("%s $%02X,X", in.Mnemonic, in.Operand)
+	case ZeroPageY:
+		return fmt.Sprintf("%s $%02X,Y", in.Mnemonic, in.Operand)
+	case Absolute:
+		return fmt.Sprintf("%s $%04X", in.Mnemonic, in.Operand)
+	case AbsoluteX:
+		return fmt.Sprintf("%s $%04X,X", in.Mnemonic, in.Operand)
+	case AbsoluteY:
+		return fmt.Sprintf("%s $%04X,Y", in.Mnemonic, in.Operand)
+	case Indirect:
+		return fmt.Sprintf("%s ($%04X)", in.Mnemonic, in.Operand)
+	case IndirectX:
+		return fmt.Sprintf("%s ($%02X,X)", in.Mnemonic, in.Operand)
+	case IndirectY:
+		return fmt.Sprintf("%s ($%02X),Y", in.Mnemonic, in.Operand)
+	case Relative:
+		return fmt.Sprintf("%s $%04X", in.Mnemonic, in.Target)
+	}
+	return in.Mnemonic
+}
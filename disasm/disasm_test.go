@@ -0,0 +1,167 @@
+package disasm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// image returns a 64K address space with code at address
+func image(address int, code ...byte) []byte {
+	mem := make([]byte, 0x10000)
+	copy(mem[address:], code)
+	return mem
+}
+
+func TestDecodeModes(t *testing.T) {
+	tests := []struct {
+		code   []byte
+		mode   Mode
+		length int
+		target uint16
+		known  bool
+		text   string
+	}{
+		{[]byte{0xEA}, Implied, 1, 0, false, "NOP"},
+		{[]byte{0x0A}, Accumulator, 1, 0, false, "ASL"},
+		{[]byte{0xA9, 0x7F}, Immediate, 2, 0, false, "LDA #$7F"},
+		{[]byte{0xA5, 0x10}, ZeroPage, 2, 0x0010, true, "LDA $10"},
+		{[]byte{0xB5, 0x10}, ZeroPageX, 2, 0x0010, true, "LDA $10,X"},
+		{[]byte{0xB6, 0x10}, ZeroPageY, 2, 0x0010, true, "LDX $10,Y"},
+		{[]byte{0xAD, 0x34, 0x12}, Absolute, 3, 0x1234, true, "LDA $1234"},
+		{[]byte{0xAD, 0x10, 0x00}, Absolute, 3, 0x0010, true, "LDA $0010"},
+		{[]byte{0xBD, 0x34, 0x12}, AbsoluteX, 3, 0x1234, true, "LDA $1234,X"},
+		{[]byte{0xB9, 0x34, 0x12}, AbsoluteY, 3, 0x1234, true, "LDA $1234,Y"},
+		{[]byte{0xA1, 0x20}, IndirectX, 2, 0x0020, true, "LDA ($20,X)"},
+		{[]byte{0xB1, 0x20}, IndirectY, 2, 0x0020, true, "LDA ($20),Y"},
+		{[]byte{0xD0, 0x10}, Relative, 2, 0x4012, true, "BNE $4012"},
+		{[]byte{0x6C, 0x00, 0x30}, Indirect, 3, 0x0000, true, "JMP ($3000)"},
+	}
+	for _, test := range tests {
+		in, err := Decode(image(0x4000, test.code...), 0x4000)
+		if err != nil {
+			t.Errorf("% X: %v", test.code, err)
+			continue
+		}
+		if in.Mode != test.mode || in.Length != test.length || in.Target != test.target ||
+			in.TargetKnown != test.known || in.Text != test.text {
+			t.Errorf("% X decoded as %s %d bytes target $%04X %v %q, want %s %d bytes target $%04X %v %q",
+				test.code, in.Mode, in.Length, in.Target, in.TargetKnown, in.Text,
+				test.mode, test.length, test.target, test.known, test.text)
+		}
+		var bytes []int
+		for _, b := range test.code {
+			bytes = append(bytes, int(b))
+		}
+		if !reflect.DeepEqual(in.Bytes, bytes) {
+			t.Errorf("% X has bytes %v", test.code, in.Bytes)
+		}
+	}
+}
+
+func TestDecodeTiming(t *testing.T) {
+	tests := []struct {
+		code                          []byte
+		cycles, pageCross, branchTake int
+	}{
+		{[]byte{0xBD, 0x00, 0x10}, 4, 1, 0},
+		{[]byte{0x9D, 0x00, 0x10}, 5, 0, 0},
+		{[]byte{0xF0, 0x00}, 2, 1, 1},
+		{[]byte{0x00}, 7, 0, 0},
+	}
+	for _, test := range tests {
+		in, err := Decode(image(0, test.code...), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if in.Cycles != test.cycles || in.PageCrossCycles != test.pageCross || in.BranchTakenCycles != test.branchTake {
+			t.Errorf("% X takes %d+%d+%d cycles, want %d+%d+%d", test.code, in.Cycles, in.PageCrossCycles,
+				in.BranchTakenCycles, test.cycles, test.pageCross, test.branchTake)
+		}
+	}
+}
+
+func TestDecodeBranchTargets(t *testing.T) {
+	tests := []struct {
+		address int
+		offset  byte
+		target  uint16
+	}{
+		{0x4000, 0x10, 0x4012},
+		{0x4000, 0x7F, 0x4081},
+		{0x4000, 0xFE, 0x4000}, // Branch to itself
+		{0x4000, 0x80, 0x3F82}, // Furthest backwards
+		{0x40FD, 0x05, 0x4104}, // Forwards across a page
+		{0xFFF0, 0x20, 0x0012}, // Wraps past $FFFF
+		{0xFFFE, 0x00, 0x0000}, // The next instruction would be at $10000
+		{0x0002, 0xF0, 0xFFF4}, // Wraps below $0000
+	}
+	for _, test := range tests {
+		in, err := Decode(image(test.address, 0x90, test.offset), uint16(test.address))
+		if err != nil {
+			t.Errorf("BCC at $%04X: %v", test.address, err)
+			continue
+		}
+		if !in.TargetKnown || in.Target != test.target {
+			t.Errorf("BCC at $%04X with offset $%02X goes to $%04X, want $%04X", test.address, test.offset, in.Target,
+				test.target)
+		}
+	}
+}
+
+func TestDecodeIndirectJump(t *testing.T) {
+	mem := image(0x4000, 0x6C, 0xFF, 0x30)
+	mem[0x30FF], mem[0x3000], mem[0x3100] = 0x34, 0x12, 0x56
+	in, err := Decode(mem, 0x4000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The high byte comes from $3000, not $3100, as the NMOS 6502 does not carry into the pointer's high byte
+	if in.Target != 0x1234 {
+		t.Errorf("JMP ($30FF) goes to $%04X, want $1234", in.Target)
+	}
+	mem = image(0x4000, 0x6C, 0x00, 0x30)
+	mem[0x3000], mem[0x3001] = 0x78, 0x56
+	if in, _ = Decode(mem, 0x4000); in.Target != 0x5678 {
+		t.Errorf("JMP ($3000) goes to $%04X, want $5678", in.Target)
+	}
+	// A pointer outside a short image leaves the target unknown
+	if in, err = Decode([]byte{0x6C, 0x00, 0x30}, 0); err != nil || in.TargetKnown {
+		t.Errorf("JMP ($3000) in a 3 byte image has a known target $%04X, error %v", in.Target, err)
+	}
+}
+
+func TestDecodeUndefined(t *testing.T) {
+	for _, opcode := range []byte{0x02, 0x03, 0x1A, 0x80, 0xFF} {
+		in, err := Decode(image(0x4000, opcode, 0x10, 0x20), 0x4000)
+		if !errors.Is(err, ErrUndefined) {
+			t.Errorf("$%02X gave error %v, want ErrUndefined", opcode, err)
+		}
+		if in.Length != 1 || in.Mnemonic != "" || !reflect.DeepEqual(in.Bytes, []int{int(opcode)}) {
+			t.Errorf("$%02X decoded as %q with %d bytes %v, want one undefined byte", opcode, in.Mnemonic, in.Length,
+				in.Bytes)
+		}
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	tests := []struct {
+		mem     []byte
+		address uint16
+	}{
+		{[]byte{0xAD, 0x10}, 0},       // Absolute missing its high byte
+		{[]byte{0xEA, 0xA9}, 1},       // Immediate missing its operand
+		{[]byte{0xEA}, 1},             // Past the end
+		{[]byte{}, 0},                 // Empty
+		{image(0xFFFE, 0x4C), 0xFFFE}, // Runs past $FFFF
+	}
+	for _, test := range tests {
+		if _, err := Decode(test.mem, test.address); !errors.Is(err, ErrTruncated) {
+			t.Errorf("% X at %d gave error %v, want ErrTruncated", test.mem, test.address, err)
+		}
+	}
+	// An instruction that ends exactly at the end of the buffer is complete
+	if in, err := Decode([]byte{0xEA, 0xAD, 0x34, 0x12}, 1); err != nil || in.Text != "LDA $1234" {
+		t.Errorf("LDA $1234 at the end of the buffer decoded as %q, error %v", in.Text, err)
+	}
+}
@@ -0,0 +1,25 @@
+package disasm_test
+
+import (
+	"fmt"
+
+	"github.com/IntuitionAmiga/six5go2/disasm"
+)
+
+func ExampleDecode() {
+	memory := make([]byte, 0x10000)
+	copy(memory[0x4000:], []byte{0xA9, 0x01, 0x8D, 0x20, 0xD0, 0xD0, 0xF9})
+	for address := uint16(0x4000); address < 0x4007; {
+		in, err := disasm.Decode(memory, address)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("$%04X  %-12s %s, %d cycles\n", in.Address, in.Text, in.Mode, in.Cycles)
+		address += uint16(in.Length)
+	}
+	// Output:
+	// $4000  LDA #$01     immediate, 2 cycles
+	// $4002  STA $D020    absolute, 4 cycles
+	// $4005  BNE $4000    relative, 2 cycles
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Static disassembler
+//
+// Unlike the dis mode, which prints each instruction as execute() runs it, the static disassembler only ever
+// reads from memory. It never touches the CPU registers, the stack or the program counter, so the program's
+// side effects never happen and every byte in the range is listed exactly once.
+
+// output receives the static disassembly, standard output unless out= names a file
+var output io.Writer = os.Stdout
+
+// readWord returns the little endian 16 bit word stored at address, wrapping at the top of memory
+func readWord(address int) int {
+	return int(memory[(address+1)&0xFFFF])<<8 | int(memory[address&0xFFFF])
+}
+
+// branchTarget returns the full 16 bit destination of the relative branch at address
+func branchTarget(address int) int {
+	return (address + 2 + int(int8(memory[(address+1)&0xFFFF]))) & 0xFFFF
+}
+
+// operandAddress returns the memory address referenced by the operand of the instruction at address
+func operandAddress(info opcodeInfo, address int) (int, bool) {
+	switch info.mode {
+	case ZEROPAGE, ZEROPAGEX, ZEROPAGEY, INDIRECTX, INDIRECTY:
+		return int(memory[(address+1)&0xFFFF]), true
+	case ABSOLUTE, ABSOLUTEX, ABSOLUTEY, INDIRECT:
+		return readWord(address + 1), true
+	case RELATIVE:
+		return branchTarget(address), true
+	}
+	return 0, false
+}
+
+// formatOperand returns the operand of the instruction at address in standard 6502 assembler notation.
+// Addresses with a label or an imported symbol are written by name.
+func formatOperand(info opcodeInfo, address int) string {
+	if info.mode == IMMEDIATE {
+		return fmt.Sprintf("#$%02X", memory[(address+1)&0xFFFF])
+	}
+	target, ok := operandAddress(info, address)
+	if !ok {
+		// Implied and accumulator instructions have no operand
+		return ""
+	}
+	name, named := addressName(target)
+	if !named {
+		if info.length == 2 && info.mode != RELATIVE {
+			name = fmt.Sprintf("$%02X", target)
+		} else {
+			name = fmt.Sprintf("$%04X", target)
+		}
+	}
+	switch info.mode {
+	case ZEROPAGEX, ABSOLUTEX:
+		return name + ",X"
+	case ZEROPAGEY, ABSOLUTEY:
+		return name + ",Y"
+	case INDIRECT:
+		return "(" + name + ")"
+	case INDIRECTX:
+		return "(" + name + ",X)"
+	case INDIRECTY:
+		return "(" + name + "),Y"
+	}
+	return name
+}
+
+// printHexComment prints the address, raw bytes and addressing mode of an instruction as a comment line
+func printHexComment(address int, length int, mode string) {
+	text := fmt.Sprintf("%s $%04x\t", syntax.comment, address)
+	for i := 0; i < length; i++ {
+		text += fmt.Sprintf("$%02x ", memory[(address+i)&0xFFFF])
+	}
+	writeLine("comment", -1, fmt.Sprintf("%s\t(%s)", text, modeNames[mode]))
+}
+
+// disassembleInstruction prints the instruction at address and returns its length in bytes.
+// Undefined opcodes, and instructions that would run past end, are printed as data.
+func disassembleInstruction(address int, end int) int {
+	info := opcodes[memory[address&0xFFFF]]
+	if info.mnemonic == "" || address+info.length-1 > end {
+		printData(address, 1)
+		return 1
+	}
+	if printHex {
+		printHexComment(address, info.length, info.mode)
+	}
+	writeLine("code", address, syntax.indent+formatInstruction(info, address))
+	return info.length
+}
+
+// disassembleLinear decodes every byte from start to end inclusive in a single linear sweep
+func disassembleLinear(start int, end int) {
+	opcodeStart := linearStarts(start, end)
+	collectLabels(start, opcodeStart)
+	printListing(start, end, opcodeStart)
+	printReports(start, opcodeStart, []int{start})
+}
+
+// printReports follows a static disassembly with the cross reference report and graphs when they were requested
+func printReports(start int, opcodeStart []bool, entries []int) {
+	// The report is assembler comments, which have no place in JSON
+	if xrefReport && !jsonOutput {
+		buildXrefs(start, opcodeStart)
+		printXrefReport()
+	}
+	if dotPrefix != "" {
+		if err := writeGraphs(dotPrefix, start, opcodeStart, entries); err != nil {
+			fmt.Printf("Cannot write graphs: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
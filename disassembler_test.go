@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden disassemblies in testdata")
+
+// loadEveryOpcode fills memory from $1000 with every opcode, first with operands that fit in zero page and then
+// with 16 bit ones, and returns the last address written
+func loadEveryOpcode() int {
+	memory = [65536]byte{}
+	labels = map[int]string{}
+	address := 0x1000
+	for _, operand := range [][]byte{{0x10, 0x00}, {0x34, 0x12}} {
+		for opcode := 0; opcode < 256; opcode++ {
+			memory[address] = byte(opcode)
+			address++
+			for i := 1; i < opcodes[byte(opcode)].length; i++ {
+				memory[address] = operand[i-1]
+				address++
+			}
+		}
+	}
+	return address - 1
+}
+
+// disassembleWith returns the linear sweep disassembly of start to end in a syntax
+func disassembleWith(t *testing.T, name string, start int, end int) string {
+	t.Helper()
+	saved, savedOutput := syntax, output
+	defer func() {
+		syntax, output = saved, savedOutput
+	}()
+	var text bytes.Buffer
+	syntax, output = syntaxes[name], &text
+	disassembleLinear(start, end)
+	return text.String()
+}
+
+func TestDisassemblyForcesAbsolute(t *testing.T) {
+	for name, s := range syntaxes {
+		memory, labels = [65536]byte{}, map[int]string{}
+		copy(memory[0x1000:], []byte{0xAD, 0x10, 0x00})
+		text := disassembleWith(t, name, 0x1000, 0x1002)
+		// LDA $0010 as AD 10 00 needs the marker of the syntax to stay absolute
+		forced := "LDA " + s.absPrefix + "$0010"
+		if s.absSuffix != "" {
+			forced = "LDA" + s.absSuffix + " $0010"
+		}
+		if s.lowercase {
+			forced = strings.ToLower(forced)
+		}
+		if !strings.Contains(text, forced) {
+			t.Errorf("%s syntax does not write %q for AD 10 00", name, forced)
+		}
+	}
+}
+
+func TestDisassemblyReassembles(t *testing.T) {
+	end := loadEveryOpcode()
+	want := append([]byte(nil), memory[0x1000:end+1]...)
+	filename := filepath.Join(t.TempDir(), "every.s")
+	if err := os.WriteFile(filename, []byte(disassembleWith(t, "six5go2", 0x1000, end)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := assemble(filename, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := a.code[a.low : a.high+1]
+	if a.low != 0x1000 || !bytes.Equal(code, want) {
+		for i := range want {
+			if i >= len(code) || code[i] != want[i] {
+				t.Fatalf("the six5go2 disassembly reassembles differently from $%04X", 0x1000+i)
+			}
+		}
+		t.Fatalf("the six5go2 disassembly reassembles to $%04X-$%04X, want $1000-$%04X", a.low, a.high, end)
+	}
+}
+
+func TestDisassemblyAtEndOfMemory(t *testing.T) {
+	savedLoad, savedFile := loadAddress, file
+	defer func() {
+		loadAddress, file = savedLoad, savedFile
+	}()
+	memory, labels = [65536]byte{}, map[int]string{}
+	// A file of JSR $2020 loaded at $FFF0 runs 16 bytes past the end of memory
+	loadAddress, file = 0xFFF0, bytes.Repeat([]byte{0x20}, 32)
+	copy(memory[loadAddress:], file)
+	if end := fileEnd(); end != 0xFFFF {
+		t.Fatalf("a file past the end of memory ends at $%X, want $FFFF", end)
+	}
+	if text := disassembleWith(t, "six5go2", loadAddress, fileEnd()); !strings.Contains(text, "JSR sub_2020\n.byte $20") {
+		t.Errorf("the linear sweep of the end of memory is %q", text)
+	}
+	saved := output
+	defer func() {
+		output = saved
+	}()
+	var text bytes.Buffer
+	output = &text
+	disassembleFlow([]int{loadAddress}, loadAddress, fileEnd())
+	if !strings.Contains(text.String(), "JSR sub_2020\n.byte $20") {
+		t.Errorf("the flow disassembly of the end of memory is %q", text.String())
+	}
+}
+
+// loadSyntaxImage loads a small program at $1000 with forced absolute operands, a call outside the image that needs
+// an equate and hinted text, word and byte data, and returns its last address
+func loadSyntaxImage(t *testing.T) int {
+	t.Helper()
+	memory, labels, regions = [65536]byte{}, map[int]string{}, [65536]*region{}
+	code := []byte{
+		0xA9, 0x01, // LDA #$01
+		0xAD, 0x10, 0x00, // LDA $0010 as absolute
+		0x9D, 0x20, 0x00, // STA $0020,X as absolute
+		0x20, 0x16, 0x10, // JSR $1016
+		0x20, 0x00, 0x20, // JSR $2000, outside the image
+		0xD0, 0xF0, // BNE $1000
+		0x4C, 0x00, 0x10, // JMP $1000
+		0x02, 0x00, 0x00, // An undefined opcode and BRKs
+		0x60,                         // RTS
+		0x48, 0x45, 0x4C, 0x4C, 0x4F, // "HELLO"
+		0x00, 0x16, 0x10, 0x01, 0x02, 0x03,
+	}
+	copy(memory[0x1000:], code)
+	for _, hint := range []string{"text 1017-101C", "words 101D-101E", "bytes 101F-1021"} {
+		if err := parseHint(strings.Fields(hint)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return 0x1000 + len(code) - 1
+}
+
+func TestDisassemblySyntaxGolden(t *testing.T) {
+	defer func() {
+		regions, printHex = [65536]*region{}, false
+	}()
+	for name := range syntaxes {
+		end := loadSyntaxImage(t)
+		printHex = true
+		text := disassembleWith(t, name, 0x1000, end)
+		printHex = false
+		golden := filepath.Join("testdata", "syntax_"+name+".s")
+		if *update {
+			if err := os.WriteFile(golden, []byte(text), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != string(want) {
+			t.Errorf("%s syntax differs from %s, run go test -update to rewrite it:\n%s", name, golden, text)
+		}
+	}
+}
+
+// assemblers holds the command that assembles a file in each syntax into a raw binary. The first word is the
+// program that must be on PATH.
+var assemblers = map[string]func(source string, binary string) []string{
+	"ca65": func(source string, binary string) []string {
+		return []string{"cl65", "-t", "none", "-o", binary, source}
+	},
+	"acme": func(source string, binary string) []string {
+		return []string{"acme", "-f", "plain", "-o", binary, source}
+	},
+	"64tass": func(source string, binary string) []string {
+		return []string{"64tass", "--quiet", "--nostart", "-o", binary, source}
+	},
+	"dasm": func(source string, binary string) []string {
+		return []string{"dasm", source, "-f3", "-o" + binary}
+	},
+	"kick": func(source string, binary string) []string {
+		return []string{"kickass", "-binfile", "-o", binary, source}
+	},
+}
+
+func TestDisassemblyReassemblesInEverySyntax(t *testing.T) {
+	defer func() {
+		regions = [65536]*region{}
+	}()
+	for name, command := range assemblers {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			source, binary := filepath.Join(dir, "test.s"), filepath.Join(dir, "test.bin")
+			args := command(source, binary)
+			if _, err := exec.LookPath(args[0]); err != nil {
+				t.Skipf("%s is not on PATH", args[0])
+			}
+			images := map[string]func() int{
+				"the syntax test image": func() int { return loadSyntaxImage(t) },
+				"every opcode": func() int {
+					regions = [65536]*region{}
+					return loadEveryOpcode()
+				},
+			}
+			for image, load := range images {
+				end := load()
+				want := append([]byte(nil), memory[0x1000:end+1]...)
+				if err := os.WriteFile(source, []byte(disassembleWith(t, name, 0x1000, end)), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if output, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+					t.Fatalf("%s: %v\n%s", strings.Join(args, " "), err, output)
+				}
+				code, err := os.ReadFile(binary)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(code, want) {
+					t.Errorf("%s of %s reassembles to % X, want % X", name, image, code, want)
+				}
+			}
+		})
+	}
+}
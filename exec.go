@@ -0,0 +1,77 @@
+package main
+
+import "fmt"
+
+// Execution guided disassembly
+//
+// The exec mode runs the program in the emulator without tracing it and records every address an opcode is
+// fetched from. When the program stops, or the cycle limit is reached, the recorded instructions are listed once
+// each in address order by the static disassembler and every byte that never ran is listed as data. Code that is
+// only reached through computed jumps, or that the program writes for itself, is mapped exactly as it ran: each
+// instruction is listed with the bytes it had when it was first fetched, whatever the program later wrote over it.
+
+var (
+	executionGuided = false
+	executed        [65536]bool    // Addresses an opcode was fetched from
+	executedCode    [65536][3]byte // The instruction at each executed address when it was first fetched
+	executionEntry  = -1           // First executed address inside the listing range
+	cycleLimit      = 0            // Stop after this many cycles, 0 runs until the program stops
+)
+
+// recordExecution marks the opcode at address as executed, unless the cycle limit has already been reached
+func recordExecution(address int) bool {
+	if cycleLimit > 0 && cycleCount >= cycleLimit {
+		return true
+	}
+	address &= 0xFFFF
+	if !executed[address] {
+		for i := 0; i < opcodes[memory[address]].length; i++ {
+			executedCode[address][i] = memory[(address+i)&0xFFFF]
+		}
+	}
+	executed[address] = true
+	if executionEntry < 0 && address >= startAddress && address <= endAddress {
+		executionEntry = address
+	}
+	return false
+}
+
+// executionStarts returns the executed opcode starts from start to end inclusive
+func executionStarts(start int, end int) []bool {
+	opcodeStart := make([]bool, end-start+1)
+	for address := start; address <= end; address++ {
+		opcodeStart[address-start] = executed[address]
+	}
+	return opcodeStart
+}
+
+// restoreExecuted puts back the bytes of every instruction executed from start to end as they were fetched
+func restoreExecuted(start int, end int) {
+	for address := start; address <= end; address++ {
+		if !executed[address] {
+			continue
+		}
+		code := executedCode[address]
+		for i := 0; i < opcodes[code[0]].length; i++ {
+			memory[(address+i)&0xFFFF] = code[i]
+		}
+	}
+}
+
+// disassembleExecuted lists the code executed between startAddress and endAddress. Memory is decoded as the program
+// left it, apart from the executed instructions, which are listed as they ran.
+func disassembleExecuted(title string) {
+	fmt.Printf("\nExecuted %d instructions in %d cycles\n\n", instructionCounter, cycleCount)
+	restoreExecuted(startAddress, endAddress)
+	opcodeStart := executionStarts(startAddress, endAddress)
+	var entries []int
+	if executionEntry >= 0 {
+		entries = append(entries, executionEntry)
+		addLabel(executionEntry, false)
+	}
+	collectLabels(startAddress, opcodeStart)
+	startHTML(title)
+	printListing(startAddress, endAddress, opcodeStart)
+	printReports(startAddress, opcodeStart, entries)
+	finishHTML()
+}
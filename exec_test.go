@@ -0,0 +1,23 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExecutedCodeIsListedAsItRan(t *testing.T) {
+	defer func() {
+		executed, executedCode = [65536]bool{}, [65536][3]byte{}
+	}()
+	memory, labels = [65536]byte{}, map[int]string{}
+	// LDX #$01 at $1000 runs and is then overwritten with NOP NOP, which never run
+	copy(memory[0x1000:], []byte{0xA2, 0x01, 0x60})
+	recordExecution(0x1000)
+	recordExecution(0x1002)
+	memory[0x1000], memory[0x1001] = 0xEA, 0xEA
+	restoreExecuted(0x1000, 0x1002)
+	text := disassembleWith(t, "six5go2", 0x1000, 0x1002)
+	if !strings.Contains(text, "LDX #$01\nRTS") {
+		t.Errorf("self modified code is listed as %q, want LDX #$01 and RTS", text)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+// Recursive descent disassembler
+//
+// Starting from a set of entry points, the flow analysis follows every path the CPU could take: both sides of a
+// branch, JMP and JSR targets, and JMP (indirect) when the pointer lies inside the loaded image. Decoding stops at
+// RTS, RTI, BRK, undefined opcodes, unconditional jumps and hinted data. Any byte never reached this way is treated
+// as data.
+
+// flowAnalysis records which bytes of the range [start, end] were reached as code
+type flowAnalysis struct {
+	start       int
+	end         int
+	opcodeStart []bool // True where an instruction begins
+}
+
+func (f *flowAnalysis) inRange(address int) bool {
+	return address >= f.start && address <= f.end
+}
+
+// vectorEntryPoints returns the NMI, RESET and IRQ/BRK handler addresses stored at $FFFA-$FFFF
+func vectorEntryPoints() []int {
+	return []int{readWord(0xFFFA), readWord(0xFFFC), readWord(0xFFFE)}
+}
+
+// analyseFlow traces all code reachable from entries without executing any of it
+func analyseFlow(entries []int, start int, end int) *flowAnalysis {
+	f := &flowAnalysis{
+		start:       start,
+		end:         end,
+		opcodeStart: make([]bool, end-start+1),
+	}
+	pending := append([]int{}, entries...)
+	for len(pending) > 0 {
+		address := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		// Follow this path until it ends or joins code that has already been traced
+		for f.inRange(address) && !f.opcodeStart[address-start] && regions[address] == nil {
+			info := opcodes[memory[address]]
+			if info.mnemonic == "" || address+info.length-1 > end {
+				break
+			}
+			f.opcodeStart[address-start] = true
+			next := address + info.length
+			switch {
+			case info.mode == RELATIVE:
+				pending = append(pending, branchTarget(address))
+			case info.mnemonic == "JSR":
+				pending = append(pending, readWord(address+1))
+				// Skip any inline data the subroutine reads from after the call
+				if r := inlineRegion(info, address); r != nil {
+					next += r.length
+				}
+			case info.mnemonic == "JMP" && info.mode == ABSOLUTE:
+				pending = append(pending, readWord(address+1))
+				next = -1
+			case info.mnemonic == "JMP" && info.mode == INDIRECT:
+				// The pointer can only be trusted when it is part of the loaded image
+				pointer := readWord(address + 1)
+				if f.inRange(pointer) && f.inRange(pointer+1) {
+					pending = append(pending, readWord(pointer))
+				}
+				next = -1
+			case info.mnemonic == "RTS" || info.mnemonic == "RTI" || info.mnemonic == "BRK":
+				next = -1
+			}
+			address = next
+		}
+	}
+	return f
+}
+
+// disassembleFlow prints the range [start, end] with reachable code as instructions and everything else as data
+func disassembleFlow(entries []int, start int, end int) {
+	f := analyseFlow(entries, start, end)
+	for _, entry := range entries {
+		if f.inRange(entry) {
+			addLabel(entry, false)
+		}
+	}
+	collectLabels(start, f.opcodeStart)
+	printListing(start, end, f.opcodeStart)
+	printReports(start, f.opcodeStart, entries)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Control flow and call graphs
+//
+// The decoded instructions are split into basic blocks, straight runs of code with a single entry at the top and
+// a single exit at the bottom. Each subroutine, an entry point or a JSR target, owns the blocks reachable from it
+// without following calls. Its control flow graph is written as one DOT file and the calls between subroutines as
+// another, ready for Graphviz.
+
+type basicBlock struct {
+	start        int
+	instructions []int // Address of every instruction in the block
+	successors   []int // Blocks control can pass to
+	calls        []int // Subroutines called with JSR from the block
+}
+
+// dotPrefix is the file name prefix for the DOT files, no graphs are written when it is empty
+var dotPrefix string
+
+// endsBlock reports whether control never falls through to the next instruction after info
+func endsBlock(info opcodeInfo) bool {
+	switch info.mnemonic {
+	case "JMP", "RTS", "RTI", "BRK":
+		return true
+	}
+	return false
+}
+
+// buildBlocks splits the instructions marked in opcodeStart into basic blocks keyed by their first address
+func buildBlocks(start int, opcodeStart []bool, roots []int) map[int]*basicBlock {
+	isCode := func(address int) bool {
+		return address >= start && address < start+len(opcodeStart) && opcodeStart[address-start]
+	}
+	// A block starts at every entry point, every branch or jump target and after every branch
+	leaders := map[int]bool{}
+	for _, root := range roots {
+		leaders[root] = true
+	}
+	for i, isStart := range opcodeStart {
+		if !isStart {
+			continue
+		}
+		address := start + i
+		info := opcodes[memory[address]]
+		if target, ok := codeTarget(info, address); ok {
+			leaders[target] = true
+		}
+		if info.mode == RELATIVE || endsBlock(info) {
+			leaders[address+info.length] = true
+		}
+		if r := inlineRegion(info, address); r != nil {
+			leaders[r.start+r.length] = true
+		}
+	}
+
+	blocks := map[int]*basicBlock{}
+	for leader := range leaders {
+		if !isCode(leader) {
+			continue
+		}
+		b := &basicBlock{start: leader}
+		address := leader
+		for {
+			info := opcodes[memory[address]]
+			b.instructions = append(b.instructions, address)
+			next := address + info.length
+			if r := inlineRegion(info, address); r != nil {
+				next += r.length
+			}
+			switch {
+			case info.mode == RELATIVE:
+				b.successors = append(b.successors, branchTarget(address), next)
+			case info.mnemonic == "JMP" && info.mode == ABSOLUTE:
+				b.successors = append(b.successors, readWord(address+1))
+			case info.mnemonic == "JSR":
+				b.calls = append(b.calls, readWord(address+1))
+			}
+			if info.mode == RELATIVE || endsBlock(info) {
+				break
+			}
+			if leaders[next] || !isCode(next) {
+				if isCode(next) {
+					b.successors = append(b.successors, next)
+				}
+				break
+			}
+			address = next
+		}
+		blocks[leader] = b
+	}
+	return blocks
+}
+
+// subroutineBlocks returns the blocks reachable from root without following calls, in address order. A jump to
+// another root is a tail call into that subroutine, whose blocks are its own.
+func subroutineBlocks(root int, blocks map[int]*basicBlock, roots map[int]bool) []*basicBlock {
+	seen := map[int]bool{}
+	pending := []int{root}
+	var reached []*basicBlock
+	for len(pending) > 0 {
+		address := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		b, ok := blocks[address]
+		if !ok || seen[address] || roots[address] && address != root {
+			continue
+		}
+		seen[address] = true
+		reached = append(reached, b)
+		pending = append(pending, b.successors...)
+	}
+	sort.Slice(reached, func(i, j int) bool { return reached[i].start < reached[j].start })
+	return reached
+}
+
+// dotName returns the name of address for use in a graph
+func dotName(address int) string {
+	if name, ok := addressName(address); ok {
+		return name
+	}
+	return fmt.Sprintf("$%04X", address)
+}
+
+// dotEscape escapes text for a DOT string
+func dotEscape(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(text)
+}
+
+// dotFileName returns name with every character that is not safe in a file name replaced by _
+func dotFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '$':
+			return -1
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// writeGraphs writes a control flow graph for each subroutine and the program call graph
+func writeGraphs(prefix string, start int, opcodeStart []bool, entries []int) error {
+	// Every entry point and every JSR target is the root of a subroutine
+	rootSet := map[int]bool{}
+	for _, entry := range entries {
+		rootSet[entry] = true
+	}
+	for i, isStart := range opcodeStart {
+		if isStart && memory[start+i] == 0x20 {
+			rootSet[readWord(start+i+1)] = true
+		}
+	}
+	var roots []int
+	for root := range rootSet {
+		roots = append(roots, root)
+	}
+	sort.Ints(roots)
+	blocks := buildBlocks(start, opcodeStart, roots)
+
+	calls, err := os.Create(prefix + "_calls.dot")
+	if err != nil {
+		return err
+	}
+	defer calls.Close()
+	fmt.Fprintf(calls, "digraph calls {\n\tnode [shape=box fontname=\"monospace\"];\n")
+
+	for _, root := range roots {
+		if _, ok := blocks[root]; !ok {
+			continue
+		}
+		name := dotName(root)
+		fmt.Fprintf(calls, "\t\"%s\";\n", dotEscape(name))
+		called := map[int]bool{}
+
+		cfg, err := os.Create(fmt.Sprintf("%s_%s.dot", prefix, dotFileName(name)))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cfg, "digraph \"%s\" {\n\tnode [shape=box fontname=\"monospace\"];\n", dotEscape(name))
+		for _, b := range subroutineBlocks(root, blocks, rootSet) {
+			text := ""
+			if name, ok := addressName(b.start); ok {
+				text = dotEscape(name) + ":\\l"
+			}
+			for _, address := range b.instructions {
+				text += fmt.Sprintf("%04X  %s\\l", address, dotEscape(formatInstruction(opcodes[memory[address]], address)))
+			}
+			fmt.Fprintf(cfg, "\tb%04X [label=\"%s\"];\n", b.start, text)
+			for _, successor := range b.successors {
+				if _, ok := blocks[successor]; !ok {
+					continue
+				}
+				if rootSet[successor] && successor != root {
+					// A jump into another subroutine is a tail call, shown as the name of the subroutine
+					fmt.Fprintf(calls, "\t\"%s\" -> \"%s\" [style=dashed];\n", dotEscape(name), dotEscape(dotName(successor)))
+					fmt.Fprintf(cfg, "\ts%04X [label=\"%s\" shape=ellipse];\n", successor, dotEscape(dotName(successor)))
+					fmt.Fprintf(cfg, "\tb%04X -> s%04X [style=dashed];\n", b.start, successor)
+					continue
+				}
+				fmt.Fprintf(cfg, "\tb%04X -> b%04X;\n", b.start, successor)
+			}
+			for _, callee := range b.calls {
+				if !called[callee] {
+					called[callee] = true
+					fmt.Fprintf(calls, "\t\"%s\" -> \"%s\";\n", dotEscape(name), dotEscape(dotName(callee)))
+				}
+			}
+		}
+		fmt.Fprintf(cfg, "}\n")
+		if err := cfg.Close(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(calls, "}\n")
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGraphsStopAtTailCalls(t *testing.T) {
+	memory, labels = [65536]byte{}, map[int]string{0x1000: "main", 0x1010: "draw/\"line\""}
+	// main tail calls draw/"line" with a JMP, draw/"line" is a subroutine of its own
+	copy(memory[0x1000:], []byte{0xA9, 0x01, 0x4C, 0x10, 0x10})
+	copy(memory[0x1010:], []byte{0xEA, 0x60})
+	opcodeStart := make([]bool, 0x20)
+	for _, address := range []int{0x1000, 0x1002, 0x1010, 0x1011} {
+		opcodeStart[address-0x1000] = true
+	}
+	prefix := filepath.Join(t.TempDir(), "test")
+	if err := writeGraphs(prefix, 0x1000, opcodeStart, []int{0x1000, 0x1010}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(prefix + "_main.dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := string(data)
+	if strings.Contains(cfg, "NOP") {
+		t.Errorf("the graph of main holds the blocks of the subroutine it tail calls:\n%s", cfg)
+	}
+	if !strings.Contains(cfg, `s1010 [label="draw/\"line\"" shape=ellipse]`) {
+		t.Errorf("the graph of main does not show the tail call:\n%s", cfg)
+	}
+	data, err = os.ReadFile(prefix + "_draw__line_.dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg := string(data); !strings.Contains(cfg, `label="draw/\"line\":\l1010  NOP\l1011  RTS\l"`) {
+		t.Errorf("the graph of draw/\"line\" is\n%s", cfg)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Disassembler hints
+//
+// A hints file tells the static disassemblers what a range of memory holds when that cannot be worked out from
+// the code alone. Addresses are hex, ranges are written first-last and anything after a ; is a comment.
+//
+//	code 4000			Decode as code, the recursive descent disassembler also uses it as an entry point
+//	bytes 4100-410F			Byte directives
+//	words 4110-411F			Word directives, words that match a label are written by name
+//	text 4120-412F			ASCII text
+//	petscii 4130-413F		PETSCII text
+//	pointers 4140 4148 8		Split pointer table, low bytes at 4140, high bytes at 4148, 8 entries (decimal)
+//	inline FFD2 3			Every JSR FFD2 is followed by 3 bytes of inline data (decimal)
+//	inline AB1E string		Every JSR AB1E is followed by a zero terminated string
+
+// region is a range of memory that holds data rather than code
+type region struct {
+	kind    string // bytes, words, text, petscii, low or high
+	start   int
+	length  int
+	partner int // The other half of a split pointer table
+}
+
+// inlineString marks an inline data hint as a zero terminated string
+const inlineString = -1
+
+var (
+	regions    [65536]*region  // The hinted region covering each address
+	inlineData = map[int]int{} // Subroutine address to the number of inline data bytes following each JSR to it
+	codeHints  []int           // Addresses hinted as code
+)
+
+// loadHints reads the hints in filename
+func loadHints(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), ";")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := parseHint(fields); err != nil {
+			return fmt.Errorf("%s line %d: %v", filename, lineNumber, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func parseHint(fields []string) error {
+	if len(fields) < 2 {
+		return fmt.Errorf("%s needs an address", fields[0])
+	}
+	first, last, err := parseRange(fields[1])
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(fields[0]) {
+	case "code":
+		codeHints = append(codeHints, first)
+	case "bytes", "words", "text", "petscii":
+		addRegion(strings.ToLower(fields[0]), first, last-first+1, 0)
+	case "pointers":
+		if len(fields) < 4 {
+			return fmt.Errorf("pointers needs the low table, high table and entry count")
+		}
+		high, err := strconv.ParseUint(fields[2], 16, 16)
+		if err != nil {
+			return err
+		}
+		count, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return err
+		}
+		if count <= 0 {
+			return fmt.Errorf("pointers needs a positive entry count")
+		}
+		// Each table holds one byte of every entry
+		if first+count > 0x10000 || int(high)+count > 0x10000 {
+			return fmt.Errorf("a table of %d pointers runs past $FFFF", count)
+		}
+		addRegion("low", first, count, int(high))
+		addRegion("high", int(high), count, first)
+	case "inline":
+		if len(fields) < 3 {
+			return fmt.Errorf("inline needs a byte count or string")
+		}
+		if fields[2] == "string" {
+			inlineData[first] = inlineString
+		} else if count, err := strconv.Atoi(fields[2]); err == nil {
+			inlineData[first] = count
+		} else {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown hint %s", fields[0])
+	}
+	return nil
+}
+
+// parseRange parses a single hex address or a first-last hex range
+func parseRange(text string) (int, int, error) {
+	firstText, lastText, isRange := strings.Cut(text, "-")
+	first, err := strconv.ParseUint(firstText, 16, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return int(first), int(first), nil
+	}
+	last, err := strconv.ParseUint(lastText, 16, 16)
+	if err != nil || last < first {
+		return 0, 0, fmt.Errorf("bad range %s", text)
+	}
+	return int(first), int(last), nil
+}
+
+// addRegion marks length bytes from start as data of the given kind
+func addRegion(kind string, start int, length int, partner int) *region {
+	r := &region{kind: kind, start: start, length: length, partner: partner}
+	for i := 0; i < length && start+i <= 0xFFFF; i++ {
+		regions[start+i] = r
+	}
+	return r
+}
+
+// inlineRegion returns the inline data following the JSR at address, or nil when the subroutine takes none
+func inlineRegion(info opcodeInfo, address int) *region {
+	if info.mnemonic != "JSR" {
+		return nil
+	}
+	count, ok := inlineData[readWord(address+1)]
+	if !ok {
+		return nil
+	}
+	start := address + 3
+	if r := regions[start&0xFFFF]; r != nil && r.start == start {
+		return r
+	}
+	if count == inlineString {
+		count = 1
+		for start+count-1 < 0xFFFF && memory[start+count-1] != 0 {
+			count++
+		}
+		return addRegion("text", start, count, 0)
+	}
+	return addRegion("bytes", start, count, 0)
+}
+
+// pointerTarget returns the address held in entry i of a split pointer table
+func pointerTarget(r *region, i int) int {
+	if r.kind == "low" {
+		return int(memory[r.partner+i])<<8 | int(memory[r.start+i])
+	}
+	return int(memory[r.start+i])<<8 | int(memory[r.partner+i])
+}
+
+// printRegion prints the directives for a hinted region
+func printRegion(r *region) {
+	switch r.kind {
+	case "words":
+		for i := 0; i+1 < r.length; i += 2 {
+			name, ok := addressName(readWord(r.start + i))
+			if !ok {
+				name = fmt.Sprintf("$%04X", readWord(r.start+i))
+			}
+			writeLine("data", r.start+i, fmt.Sprintf("%s%s %s", syntax.indent, syntax.wordDirective, name))
+		}
+		if r.length%2 == 1 {
+			printData(r.start+r.length-1, 1)
+		}
+	case "text", "petscii":
+		printText(r)
+	case "low", "high":
+		operator := "<"
+		if r.kind == "high" {
+			operator = ">"
+		}
+		for i := 0; i < r.length; i += 8 {
+			var values []string
+			for j := i; j < i+8 && j < r.length; j++ {
+				target := pointerTarget(r, j)
+				name, ok := addressName(target)
+				if !ok {
+					name = fmt.Sprintf("$%04X", target)
+				}
+				values = append(values, operator+name)
+			}
+			writeLine("data", r.start+i, fmt.Sprintf("%s%s %s", syntax.indent, syntax.byteDirective, strings.Join(values, ",")))
+		}
+	default:
+		printData(r.start, r.length)
+	}
+}
+
+// printText prints a text region with printable characters in quotes and everything else as hex bytes
+func printText(r *region) {
+	if syntax.textDirective == "" {
+		// Without a text directive that keeps bytes unchanged the text is only shown as a comment
+		writeLine("comment", -1, fmt.Sprintf("%s \"%s\"", syntax.comment, textPreview(r)))
+		printData(r.start, r.length)
+		return
+	}
+	var values []string
+	quoted := ""
+	lineStart := r.start
+	flush := func() {
+		if quoted != "" {
+			values = append(values, "\""+quoted+"\"")
+			quoted = ""
+		}
+		// Keep long strings to one directive per 32 characters
+		if len(values) > 0 {
+			writeLine("data", lineStart, fmt.Sprintf("%s%s %s", syntax.indent, syntax.textDirective, strings.Join(values, ",")))
+			values = nil
+		}
+	}
+	for i := 0; i < r.length; i++ {
+		value := memory[r.start+i]
+		if !printable(r.kind, value) {
+			if quoted != "" {
+				values = append(values, "\""+quoted+"\"")
+				quoted = ""
+			}
+			values = append(values, fmt.Sprintf("$%02X", value))
+			continue
+		}
+		quoted += string(rune(value))
+		if len(quoted) == 32 {
+			flush()
+			lineStart = r.start + i + 1
+		}
+	}
+	flush()
+}
+
+// printable reports whether value can be written inside a quoted string without changing the assembled byte
+func printable(kind string, value byte) bool {
+	if value == '"' || value == '\\' {
+		return false
+	}
+	if kind == "petscii" {
+		// PETSCII matches ASCII for space, digits, punctuation and unshifted upper case letters
+		return value >= 0x20 && value <= 0x5F
+	}
+	return value >= 0x20 && value <= 0x7E
+}
+
+// textPreview returns the printable characters of a text region for a comment
+func textPreview(r *region) string {
+	preview := ""
+	for i := 0; i < r.length; i++ {
+		if value := memory[r.start+i]; printable(r.kind, value) {
+			preview += string(rune(value))
+		} else {
+			preview += "."
+		}
+	}
+	return preview
+}
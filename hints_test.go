@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPointerHintCounts(t *testing.T) {
+	defer func() {
+		regions = [65536]*region{}
+	}()
+	tests := []struct {
+		hint string
+		ok   bool
+	}{
+		{"pointers 4140 4148 8", true},
+		{"pointers FFF8 FFFC 4", true},
+		{"pointers 4140 4148 0", false},
+		{"pointers 4140 4148 -3", false},
+		{"pointers FFF8 4000 9", false},
+		{"pointers 4000 FFFC 5", false},
+	}
+	for _, test := range tests {
+		regions = [65536]*region{}
+		if err := parseHint(strings.Fields(test.hint)); (err == nil) != test.ok {
+			t.Errorf("%q gave error %v", test.hint, err)
+		}
+	}
+}
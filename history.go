@@ -0,0 +1,160 @@
+package main
+
+import "fmt"
+
+// Execution history
+//
+// While the monitor is active the emulator records the registers and the call stack before each instruction and the
+// old value of every byte the instruction writes, for the last historyLimit instructions. Undoing the newest entries
+// puts the machine back exactly as it was before any recorded instruction, which the reverse commands of the monitor
+// use to step backwards. Changes made with monitor commands are not recorded and are not undone.
+
+type memoryWrite struct {
+	address int
+	old     byte
+}
+
+type historyEntry struct {
+	A, X, Y, SR  byte
+	SP           uint
+	PC           int
+	bytecounter  int
+	cycles       int // cycleCount before the instruction
+	instructions int // instructionCounter before the instruction
+	calls        []callFrame
+	writes       []memoryWrite
+}
+
+var (
+	historyLimit  = 100000 // Instructions kept in the history, 0 records none
+	recordHistory = false
+	history       []historyEntry // Ring of entries, the oldest at historyStart
+	historyStart  = 0
+	historyLength = 0
+)
+
+// recordInstruction adds an entry for the instruction at bytecounter, which is about to run
+func recordInstruction() {
+	if history == nil {
+		history = make([]historyEntry, historyLimit)
+	}
+	index := (historyStart + historyLength) % historyLimit
+	if historyLength == historyLimit {
+		historyStart = (historyStart + 1) % historyLimit
+	} else {
+		historyLength++
+	}
+	// The write list of an overwritten entry is reused, so a long run does not allocate for every instruction
+	history[index] = historyEntry{A: A, X: X, Y: Y, SR: SR, SP: SP, PC: PC, bytecounter: bytecounter,
+		cycles: cycleCount, instructions: instructionCounter, calls: callStack, writes: history[index].writes[:0]}
+}
+
+// recordWrite records the old value of a byte the current instruction writes
+func recordWrite(address int, old byte) {
+	if historyLength == 0 {
+		return
+	}
+	entry := &history[(historyStart+historyLength-1)%historyLimit]
+	entry.writes = append(entry.writes, memoryWrite{address, old})
+}
+
+// writtenValues returns the value each write of the newest entry stored, which is the old value of a later write to
+// the same address in that entry or else what memory holds now
+func writtenValues() []byte {
+	entry := &history[(historyStart+historyLength-1)%historyLimit]
+	values := make([]byte, len(entry.writes))
+	for i, w := range entry.writes {
+		values[i] = memory[w.address]
+		for _, later := range entry.writes[i+1:] {
+			if later.address == w.address {
+				values[i] = later.old
+				break
+			}
+		}
+	}
+	return values
+}
+
+// undoInstruction restores the machine to before the newest recorded instruction and returns its entry, or nil
+// when the history is empty
+func undoInstruction() *historyEntry {
+	if historyLength == 0 {
+		return nil
+	}
+	historyLength--
+	entry := &history[(historyStart+historyLength)%historyLimit]
+	for i := len(entry.writes) - 1; i >= 0; i-- {
+		memory[entry.writes[i].address] = entry.writes[i].old
+	}
+	A, X, Y, SR, SP, PC, bytecounter = entry.A, entry.X, entry.Y, entry.SR, entry.SP, entry.PC, entry.bytecounter
+	cycleCount, instructionCounter, callStack = entry.cycles, entry.instructions, entry.calls
+	return entry
+}
+
+// reverseStep undoes count instructions
+func reverseStep(count int) error {
+	for i := 0; i < count; i++ {
+		if undoInstruction() == nil {
+			return fmt.Errorf("the history starts here")
+		}
+	}
+	return nil
+}
+
+// reverseContinue undoes instructions until the machine is at a breakpoint whose condition holds, or before an
+// instruction whose writes hit a write or change watchpoint. Reads are not recorded, so read watchpoints are passed.
+func reverseContinue() error {
+	for historyLength > 0 {
+		values := writtenValues()
+		entry := undoInstruction()
+		if b := reverseHit(entry, values); b != nil {
+			fmt.Printf("%s %d at $%04X\n", b.title(), b.number, bytecounter)
+			return nil
+		}
+	}
+	return fmt.Errorf("the history starts here")
+}
+
+// reverseHit returns the breakpoint or watchpoint that stops a reverse continue before the instruction in entry,
+// which has just been undone, and the values its writes stored
+func reverseHit(entry *historyEntry, values []byte) *breakpoint {
+	for _, b := range breakpoints {
+		if !b.enabled {
+			continue
+		}
+		if b.kind == "exec" {
+			if b.address <= bytecounter && bytecounter <= b.end && b.conditionHolds(machineScope{}) {
+				return b
+			}
+			continue
+		}
+		for i, w := range entry.writes {
+			value := values[i]
+			if w.address < b.address || w.address > b.end || b.kind == "read" || b.kind == "change" && value == w.old {
+				continue
+			}
+			if b.conditionHolds(machineScope{access: true, address: w.address, value: value, old: w.old}) {
+				return b
+			}
+		}
+	}
+	return nil
+}
+
+// goToInstruction moves to the point where number instructions have run, backwards through the history or
+// forwards by running the program
+func goToInstruction(number int) (bool, error) {
+	if number == instructionCounter {
+		return false, nil
+	}
+	if number > instructionCounter {
+		stepsLeft = 0
+		startStep("count", number)
+		return true, nil
+	}
+	oldest := instructionCounter - historyLength
+	if number < oldest {
+		return false, fmt.Errorf("the history starts at instruction %d", oldest)
+	}
+	return false, reverseStep(instructionCounter - number)
+}
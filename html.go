@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// HTML output
+//
+// With the html option every line of the static disassembly is wrapped in a styled span inside a single self
+// contained page. Every label is an anchor, every name in an operand that matches a label links to it, and each
+// label is followed by its cross references linking back to the instructions that use it.
+
+var (
+	htmlOutput = false             // Write the static disassembly as hyperlinked HTML
+	labelNames = map[string]bool{} // Every label name that can be linked to
+)
+
+// identifier matches the names that may refer to a label
+var identifier = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_.]*`)
+
+const htmlStyle = `body { background: #1e1e1e; color: #d4d4d4; }
+pre { font-family: monospace; font-size: 14px; }
+a { color: inherit; }
+.code { color: #9cdcfe; }
+.data { color: #ce9178; }
+.comment { color: #6a9955; }
+.directive { color: #c586c0; }
+.label { color: #dcdcaa; font-weight: bold; }
+.equate { color: #dcdcaa; }
+.xref { color: #808080; }
+:target { background: #3a3d41; }
+`
+
+// startHTML writes the page header
+func startHTML(title string) {
+	if !htmlOutput {
+		return
+	}
+	fmt.Fprintf(output, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n<pre>\n", html.EscapeString(title), htmlStyle)
+}
+
+// finishHTML writes the page footer
+func finishHTML() {
+	if !htmlOutput {
+		return
+	}
+	fmt.Fprintf(output, "</pre>\n</body>\n</html>\n")
+}
+
+// writeLine writes one line of disassembly, class is code, data, comment or directive.
+// Code and data lines are anchored by address so that cross references can link to them.
+func writeLine(class string, address int, text string) {
+	if !htmlOutput {
+		fmt.Fprintf(output, "%s\n", text)
+		return
+	}
+	if text == "" {
+		fmt.Fprintf(output, "\n")
+		return
+	}
+	id := ""
+	if address >= 0 && (class == "code" || class == "data") {
+		id = fmt.Sprintf(" id=\"a%04X\"", address)
+	}
+	fmt.Fprintf(output, "<span class=\"%s\"%s>%s</span>\n", class, id, linkNames(html.EscapeString(text)))
+}
+
+// writeLabel writes the definition of the label for address, class is label or equate
+func writeLabel(class string, address int, text string) {
+	if !htmlOutput {
+		fmt.Fprintf(output, "%s\n", text)
+		return
+	}
+	name := labels[address]
+	fmt.Fprintf(output, "<span class=\"%s\" id=\"%s\">%s</span>", class, html.EscapeString(name), html.EscapeString(text))
+	if len(xrefs[address]) > 0 {
+		var references []string
+		for _, x := range xrefs[address] {
+			references = append(references, fmt.Sprintf("<a href=\"#a%04X\">$%04X</a> %s", x.from, x.from, x.kind))
+		}
+		fmt.Fprintf(output, "\t<span class=\"xref\">%s %s</span>", html.EscapeString(syntax.comment), strings.Join(references, ", "))
+	}
+	fmt.Fprintf(output, "\n")
+}
+
+// indexLabelNames records the name of every label so operands can be linked to them
+func indexLabelNames() {
+	labelNames = map[string]bool{}
+	for _, name := range labels {
+		labelNames[name] = true
+	}
+}
+
+// linkNames turns every label name in already escaped text into a link to the label
+func linkNames(text string) string {
+	return identifier.ReplaceAllStringFunc(text, func(word string) string {
+		if labelNames[word] {
+			return fmt.Sprintf("<a href=\"#%s\">%s</a>", word, word)
+		}
+		return word
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/IntuitionAmiga/six5go2/disasm"
+)
+
+// JSON output
+//
+// With the json option the static disassemblers write the decoded instructions from the disasm package instead
+// of assembler source, one object per line inside a single array, so that scripts can post-process a listing.
+// Each instruction also carries its label and its source text in the selected syntax. Data is written as objects
+// that only have an address, the bytes and the kind of data.
+
+// jsonOutput writes the static disassembly as JSON
+var jsonOutput = false
+
+type jsonLine struct {
+	disasm.Instruction
+	Kind   string `json:"kind"` // code, data, or the kind of a hinted region
+	Label  string `json:"label,omitempty"`
+	Source string `json:"source,omitempty"` // The instruction in the selected syntax, using label names
+}
+
+// printJSON writes every line of the listing from start to end inclusive as JSON
+func printJSON(start int, end int, opcodeStart []bool) {
+	first := true
+	write := func(line jsonLine) {
+		line.Label = labels[int(line.Address)]
+		encoded, err := json.Marshal(line)
+		if err != nil {
+			fmt.Printf("Cannot encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		separator := ",\n"
+		if first {
+			separator = "[\n"
+			first = false
+		}
+		fmt.Fprintf(output, "%s%s", separator, encoded)
+	}
+	data := func(kind string, address int, count int) jsonLine {
+		line := jsonLine{Instruction: disasm.Instruction{Address: uint16(address), Length: count}, Kind: kind}
+		for i := 0; i < count; i++ {
+			line.Bytes = append(line.Bytes, int(memory[address+i]))
+		}
+		return line
+	}
+
+	for address := start; address <= end; {
+		if r := regions[address]; r != nil && r.start == address {
+			write(data(r.kind, address, r.length))
+			address += r.length
+			continue
+		}
+		if opcodeStart[address-start] {
+			in, err := disasm.Decode(memory[:], uint16(address))
+			if err == nil && address+in.Length-1 <= end {
+				write(jsonLine{Instruction: in, Kind: "code", Source: formatInstruction(opcodes[memory[address]], address)})
+				address += in.Length
+				continue
+			}
+			// Undefined opcodes, and instructions that would run past end, are data
+			write(data("data", address, 1))
+			address++
+			continue
+		}
+		count := dataRunLength(address, start, end, opcodeStart)
+		for count > 0 {
+			n := count
+			if n > 8 {
+				n = 8
+			}
+			write(data("data", address, n))
+			address += n
+			count -= n
+		}
+	}
+	if first {
+		fmt.Fprintf(output, "[")
+	}
+	fmt.Fprintf(output, "\n]\n")
+}
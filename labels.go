@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Automatic labels
+//
+// Every branch, jump and call target gets a generated label so the listing reads like source code and can be fed
+// back into an assembler. Subroutines called with JSR are named sub_XXXX, every other target is named LXXXX.
+
+// labels maps a target address to its generated label
+var labels = map[int]string{}
+
+// codeTarget returns the address a branch, JMP absolute or JSR instruction at address transfers control to
+func codeTarget(info opcodeInfo, address int) (int, bool) {
+	switch {
+	case info.mode == RELATIVE:
+		return branchTarget(address), true
+	case info.mnemonic == "JSR" || info.mnemonic == "JMP" && info.mode == ABSOLUTE:
+		return readWord(address + 1), true
+	}
+	return 0, false
+}
+
+// addLabel names target, an imported symbol always wins and a subroutine name wins over a plain label
+func addLabel(target int, subroutine bool) {
+	if name, ok := symbols[target]; ok {
+		labels[target] = name
+	} else if subroutine {
+		labels[target] = fmt.Sprintf("sub_%04X", target)
+	} else if _, ok := labels[target]; !ok {
+		labels[target] = fmt.Sprintf("L%04X", target)
+	}
+}
+
+// collectLabels adds a label for the target of every instruction in the range marked in opcodeStart
+func collectLabels(start int, opcodeStart []bool) {
+	for i, isStart := range opcodeStart {
+		if !isStart {
+			continue
+		}
+		info := opcodes[memory[start+i]]
+		if target, ok := codeTarget(info, start+i); ok {
+			addLabel(target, info.mnemonic == "JSR")
+		} else if target, ok := operandAddress(info, start+i); ok {
+			// Memory operands are only named when a symbol file gives them a name
+			if name, ok := symbols[target]; ok {
+				labels[target] = name
+			}
+		}
+	}
+	// Entries in hinted pointer tables point at code or data that deserves a name
+	for address := start; address < start+len(opcodeStart); address++ {
+		if r := regions[address]; r != nil && r.start == address && r.kind == "low" {
+			for i := 0; i < r.length; i++ {
+				addLabel(pointerTarget(r, i), false)
+			}
+		}
+	}
+	// Symbols inside the range are defined even when nothing refers to them
+	for address, name := range symbols {
+		if address >= start && address < start+len(opcodeStart) {
+			labels[address] = name
+		}
+	}
+}
+
+// linearStarts marks every byte in [start, end] where the linear sweep disassembler begins an instruction
+func linearStarts(start int, end int) []bool {
+	opcodeStart := make([]bool, end-start+1)
+	for address := start; address <= end; {
+		if r := regions[address]; r != nil {
+			// Skip hinted data and carry on decoding after it
+			address = r.start + r.length
+			continue
+		}
+		info := opcodes[memory[address]]
+		if info.mnemonic == "" || address+info.length-1 > end {
+			address++
+			continue
+		}
+		opcodeStart[address-start] = true
+		address += info.length
+		if r := inlineRegion(info, address-info.length); r != nil {
+			address += r.length
+		}
+	}
+	return opcodeStart
+}
+
+// printListing prints the range [start, end] with instructions where opcodeStart is set and data elsewhere.
+// Labels that fall on a line are printed in front of it, the rest are defined as equates before the origin.
+func printListing(start int, end int, opcodeStart []bool) {
+	if jsonOutput {
+		printJSON(start, end, opcodeStart)
+		return
+	}
+	// The HTML page shows the references to each label where it is defined
+	if htmlOutput {
+		buildXrefs(start, opcodeStart)
+		indexLabelNames()
+	}
+	// Work out which addresses begin a line of output so that every other label can be defined as an equate
+	placed := map[int]bool{}
+	for address := start; address <= end; {
+		placed[address] = true
+		if r := regions[address]; r != nil && r.start == address {
+			address += r.length
+			continue
+		}
+		if opcodeStart[address-start] {
+			address += opcodes[memory[address]].length
+			continue
+		}
+		address += dataRunLength(address, start, end, opcodeStart)
+	}
+	var equates []int
+	for address := range labels {
+		if !placed[address] {
+			equates = append(equates, address)
+		}
+	}
+	sort.Ints(equates)
+	if syntax.header != "" {
+		writeLine("directive", -1, strings.TrimSuffix(syntax.header, "\n"))
+	}
+	for _, address := range equates {
+		writeLabel("equate", address, strings.TrimSuffix(fmt.Sprintf(syntax.equate, labels[address], address), "\n"))
+	}
+	if len(equates) > 0 || syntax.header != "" {
+		writeLine("", -1, "")
+	}
+
+	writeLine("directive", -1, strings.TrimSuffix(fmt.Sprintf(syntax.origin, start), "\n"))
+	writeLine("", -1, "")
+	for address := start; address <= end; {
+		if name, ok := labels[address]; ok {
+			writeLabel("label", address, name+syntax.labelSuffix)
+		}
+		if r := regions[address]; r != nil && r.start == address {
+			printRegion(r)
+			address += r.length
+			continue
+		}
+		if opcodeStart[address-start] {
+			address += disassembleInstruction(address, end)
+			continue
+		}
+		count := dataRunLength(address, start, end, opcodeStart)
+		printData(address, count)
+		address += count
+	}
+}
+
+// printData prints count bytes starting at address as byte directives, eight to a line
+func printData(address int, count int) {
+	for count > 0 {
+		n := count
+		if n > 8 {
+			n = 8
+		}
+		if printHex {
+			writeLine("comment", -1, fmt.Sprintf("%s $%04x\t\t\t(Data)", syntax.comment, address))
+		}
+		var values []string
+		for i := 0; i < n; i++ {
+			values = append(values, fmt.Sprintf("$%02X", memory[address+i]))
+		}
+		writeLine("data", address, fmt.Sprintf("%s%s %s", syntax.indent, syntax.byteDirective, strings.Join(values, ",")))
+		address += n
+		count -= n
+	}
+}
+
+// dataRunLength returns the number of data bytes from address up to the next instruction or label
+func dataRunLength(address int, start int, end int, opcodeStart []bool) int {
+	count := 1
+	for address+count <= end && !opcodeStart[address+count-start] {
+		if _, ok := labels[address+count]; ok {
+			break
+		}
+		if r := regions[address+count]; r != nil && r.start == address+count {
+			break
+		}
+		count++
+	}
+	return count
+}
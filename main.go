@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"syscall"
-	"time"
 	"unsafe"
 )
 
@@ -15,8 +15,14 @@ var (
 	bytecounter        int // Byte position counter
 	machineMonitor     = false
 	disassemble        = false
-	once               = true
-	instructionCounter = 0
+	linearSweep        = false
+	flowDisassemble    = false
+	entryPoints        []int // Extra entry points for the recursive descent disassembler
+	startAddress       = -1 // First address for the static disassembler, -1 means the load address
+	endAddress         = -1 // Last address for the static disassembler, -1 means the end of the file
+	instructionCounter = 0 // Number of instructions executed
+	instructionAddress = 0 // Address of the instruction being executed
+	cycleCount         = 0 // Base cycles of every executed instruction, page crossings are not counted
 	loadAddress        int
 	displayAddress     = 0xF001
 
@@ -42,15 +48,26 @@ const (
 	INDIRECT    = "indirect"
 	INDIRECTX   = "indirectx"
 	INDIRECTY   = "indirecty"
+	IMPLIED     = "implied"
+	RELATIVE    = "relative"
 )
 
 func main() {
+	// The debug adapter keeps standard output for the protocol, so it starts before anything is printed
+	if len(os.Args) > 1 && os.Args[1] == "dap" {
+		serveDAP(os.Args[2:])
+		os.Exit(0)
+	}
 	fmt.Printf("Six5go2 - 6502 Emulator and Disassembler in Golang (c) 2022 Zayn Otley\n\n")
 
 	if len(os.Args) <= 2 {
 		instructions()
 		os.Exit(0)
 	}
+	if os.Args[1] == "asm" {
+		assembleFile(os.Args[2], os.Args[3:])
+		os.Exit(0)
+	}
 	if len(os.Args) > 2 {
 		parseUint, _ := strconv.ParseUint(os.Args[2], 16, 16)
 		loadAddress = int(parseUint)
@@ -61,8 +78,36 @@ func main() {
 	if len(os.Args) > 3 && os.Args[3] == "mon" {
 		machineMonitor = true
 	}
-	if len(os.Args) > 4 && os.Args[4] == "hex" {
-		printHex = true
+	if len(os.Args) > 3 && os.Args[3] == "tui" {
+		machineMonitor, tuiMode = true, true
+	}
+	if len(os.Args) > 3 && os.Args[3] == "vice" {
+		machineMonitor, viceMode = true, true
+	}
+	if len(os.Args) > 3 && os.Args[3] == "lin" {
+		linearSweep = true
+	}
+	if len(os.Args) > 3 && os.Args[3] == "flow" {
+		flowDisassemble = true
+	}
+	if len(os.Args) > 3 && os.Args[3] == "exec" {
+		executionGuided = true
+	}
+	if len(os.Args) > 4 {
+		parseOptions(os.Args[4:])
+	}
+	// JSON replaces the assembler source, so it cannot also be wrapped in HTML
+	if jsonOutput {
+		htmlOutput = false
+	}
+	// Platform names are added last so that names from symbol files take priority
+	if platform != "" {
+		count, err := loadPlatform(platform)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Loaded %d %s platform names\n\n", count, platform)
 	}
 
 	fmt.Printf("Size of addressable memory is %v ($%04X) bytes\n\n", len(memory), len(memory))
@@ -75,17 +120,198 @@ func main() {
 	fmt.Printf("Copying file into memory at $%04X to $%04X\n\n", loadAddress, loadAddress+len(file))
 	copy(memory[loadAddress:], file)
 
+	// Static disassembly only reads memory so the CPU is never reset or run
+	if startAddress < 0 {
+		startAddress = loadAddress
+	}
+	if endAddress < 0 {
+		endAddress = fileEnd()
+	}
+	if endAddress > 0xFFFF {
+		endAddress = 0xFFFF
+	}
+	if endAddress < startAddress {
+		fmt.Printf("The end address $%04X is before the start address $%04X\n", endAddress, startAddress)
+		os.Exit(1)
+	}
+	if linearSweep {
+		startHTML(os.Args[1])
+		disassembleLinear(startAddress, endAddress)
+		finishHTML()
+		os.Exit(0)
+	}
+	if flowDisassemble {
+		// Follow code from the entry point, the hardware vectors and any user supplied addresses
+		entries := append([]int{loadAddress}, vectorEntryPoints()...)
+		entries = append(entries, codeHints...)
+		startHTML(os.Args[1])
+		disassembleFlow(append(entries, entryPoints...), startAddress, endAddress)
+		finishHTML()
+		os.Exit(0)
+	}
+
+	// Name the branch, jump and call targets in the file so the trace and the monitor can refer to them
+	if disassemble || machineMonitor {
+		collectLabels(loadAddress, linearStarts(loadAddress, fileEnd()))
+	}
+
 	// Start emulation
 	fmt.Printf("Starting emulation at $%04X\n\n", PC)
 	reset()
+	for _, option := range breakpointOptions {
+		fields := monitorFields(option)
+		if err := breakpointCommand(fields[0], fields[1:]); err != nil {
+			fmt.Printf("Bad breakpoint %s: %v\n", option, err)
+			os.Exit(1)
+		}
+	}
+	if machineMonitor || len(breakpoints) > 0 || monitorScript != "" {
+		startMonitor()
+	}
+	if monitorScript != "" {
+		if _, err := runScript(monitorScript); err != nil {
+			fmt.Printf("Cannot run monitor commands: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if tuiMode {
+		if err := startTUI(); err != nil {
+			fmt.Printf("The tui mode needs a terminal: %v\n", err)
+			os.Exit(1)
+		}
+		// A panic in the emulator still gives the terminal back
+		defer restoreTerminal()
+	}
+	if viceMode {
+		if err := startVice(); err != nil {
+			fmt.Printf("Cannot listen on %s: %v\n", listenAddress, err)
+			os.Exit(1)
+		}
+	}
 	printMachineState()
 	execute()
+	if executionGuided {
+		disassembleExecuted(os.Args[1])
+	}
 }
 func instructions() {
-	fmt.Printf("USAGE   - %s <target_filename> <hex_entry_point> <dis>/<mon> (Disassembler/Machine Monitor) <hex> (Hex opcodes as comments with disassembly)\n\n", os.Args[0])
+	fmt.Printf("USAGE   - %s <target_filename> <hex_entry_point> <dis>/<mon>/<tui>/<vice>/<lin>/<flow>/<exec> (Disassembler/Machine Monitor/Full Screen Debugger/VICE Binary Monitor Server/Linear Sweep Disassembler/Recursive Descent Disassembler/Execution Guided Disassembler) <hex> (Hex opcodes as comments with disassembly)\n\n", os.Args[0])
+	fmt.Printf("USAGE   - %s asm <source_filename> out=<binary_filename> sym=<symbol_filename> list=<listing_filename> (Assemble to a raw binary, VICE labels and an optional listing with cycle counts)\n\n", os.Args[0])
+	fmt.Printf("USAGE   - %s dap listen=<host:port> (Debug Adapter Protocol server for editors, over stdin and stdout without listen=)\n\n", os.Args[0])
+	fmt.Printf("OPTIONS - start=<hex_address> end=<hex_address> (Address range for the static disassemblers)\n\n")
+	fmt.Printf("OPTIONS - entry=<hex_address>,<hex_address>... (Extra code entry points for the recursive descent disassembler)\n\n")
+	fmt.Printf("OPTIONS - sym=<filename>,<filename>... (VICE labels, ca65 .dbg/.map or 64tass/ACME label lists)\n\n")
+	fmt.Printf("OPTIONS - hints=<filename> (Code, byte, word, text, pointer table and inline data regions for the static disassemblers)\n\n")
+	fmt.Printf("OPTIONS - xref (Append a cross reference report) xref=<hex_address>,<hex_address>... (Cross references for these addresses only)\n\n")
+	fmt.Printf("OPTIONS - dot=<prefix> (Write Graphviz DOT control flow graphs for each subroutine and the call graph)\n\n")
+	fmt.Printf("OPTIONS - platform=<%s> (Name hardware registers and ROM entry points)\n\n", platformNames())
+	fmt.Printf("OPTIONS - html (Write the disassembly as a self contained hyperlinked HTML page, use with out=)\n\n")
+	fmt.Printf("OPTIONS - cycles=<decimal> (Stop the execution guided disassembler after this many cycles)\n\n")
+	fmt.Printf("OPTIONS - break=<hex_address>[ after <count>][ ignore <count>][ if <condition>] (Stop in the machine monitor at this address, may be repeated)\n\n")
+	fmt.Printf("OPTIONS - watch=[read|write|change|access ]<hex_address>[ <hex_address>][ if <condition>] (Stop in the machine monitor after an access to memory, may be repeated)\n\n")
+	fmt.Printf("OPTIONS - moncommands=<filename> (Machine monitor commands to run before the first instruction)\n\n")
+	fmt.Printf("OPTIONS - history=<decimal> (Instructions the machine monitor keeps for stepping backwards, default 100000, 0 for none)\n\n")
+	fmt.Printf("OPTIONS - listen=<host:port> (Address of the VICE binary monitor server, default 127.0.0.1:6502)\n\n")
+	fmt.Printf("OPTIONS - json (Write the decoded instructions as JSON for scripts)\n\n")
+	fmt.Printf("OPTIONS - syntax=<%s> (Assembler syntax for the static disassemblers) out=<filename> (Write the disassembly to a file)\n\n", syntaxNames())
 	fmt.Printf("EXAMPLE - %s AllSuiteA.bin 4000 mon\n\n", os.Args[0])
+	fmt.Printf("EXAMPLE - %s AllSuiteA.bin 4000 tui\n\n", os.Args[0])
+	fmt.Printf("EXAMPLE - %s AllSuiteA.bin 4000 vice listen=127.0.0.1:6502\n\n", os.Args[0])
 	fmt.Printf("EXAMPLE - %s AllSuiteA.bin 4000 dis\n\n", os.Args[0])
 	fmt.Printf("EXAMPLE - %s AllSuiteA.bin 4000 dis hex\n\n", os.Args[0])
+	fmt.Printf("EXAMPLE - %s AllSuiteA.bin 4000 lin hex end=4100\n\n", os.Args[0])
+	fmt.Printf("EXAMPLE - %s AllSuiteA.bin 4000 flow entry=F000\n\n", os.Args[0])
+	fmt.Printf("EXAMPLE - %s AllSuiteA.bin 4000 flow syntax=ca65 out=AllSuiteA.s\n\n", os.Args[0])
+	fmt.Printf("EXAMPLE - %s game.bin 0801 flow sym=game.lbl platform=c64\n\n", os.Args[0])
+	fmt.Printf("EXAMPLE - %s AllSuiteA.bin 4000 exec cycles=100000\n\n", os.Args[0])
+	fmt.Printf("EXAMPLE - %s AllSuiteA.bin 4000 mon \"break=45C0 if A==$FE && mem[$0210]>0\"\n\n", os.Args[0])
+	fmt.Printf("EXAMPLE - %s AllSuiteA.bin 4000 mon moncommands=suite.mon\n\n", os.Args[0])
+	fmt.Printf("EXAMPLE - %s asm AllSuiteA.asm out=suite.bin sym=suite.lbl list=suite.lst\n\n", os.Args[0])
+	fmt.Printf("EXAMPLE - %s AllSuiteA.bin 4000 flow json out=AllSuiteA.json\n\n", os.Args[0])
+}
+
+// fileEnd returns the last address the file was loaded into, which is $FFFF for a file that runs past the end of
+// memory
+func fileEnd() int {
+	if end := loadAddress + len(file) - 1; end < 0xFFFF {
+		return end
+	}
+	return 0xFFFF
+}
+func parseOptions(options []string) {
+	for _, option := range options {
+		name, value, _ := strings.Cut(option, "=")
+		switch name {
+		case "hex":
+			printHex = true
+		case "start":
+			parseUint, _ := strconv.ParseUint(value, 16, 16)
+			startAddress = int(parseUint)
+		case "end":
+			parseUint, _ := strconv.ParseUint(value, 16, 16)
+			endAddress = int(parseUint)
+		case "syntax":
+			if _, ok := syntaxes[value]; !ok {
+				fmt.Printf("Unknown syntax %s, choose one of %s\n", value, syntaxNames())
+				os.Exit(1)
+			}
+			syntax = syntaxes[value]
+		case "out":
+			outputFile, err := os.Create(value)
+			if err != nil {
+				fmt.Printf("Cannot create %s: %v\n", value, err)
+				os.Exit(1)
+			}
+			output = outputFile
+		case "sym":
+			for _, filename := range strings.Split(value, ",") {
+				count, err := loadSymbols(filename)
+				if err != nil {
+					fmt.Printf("Cannot read symbols from %s: %v\n", filename, err)
+					os.Exit(1)
+				}
+				fmt.Printf("Loaded %d symbols from %s\n\n", count, filename)
+			}
+		case "hints":
+			if err := loadHints(value); err != nil {
+				fmt.Printf("Cannot read hints: %v\n", err)
+				os.Exit(1)
+			}
+		case "xref":
+			xrefReport = true
+			if value != "" {
+				for _, address := range strings.Split(value, ",") {
+					parseUint, _ := strconv.ParseUint(address, 16, 16)
+					xrefAddresses = append(xrefAddresses, int(parseUint))
+				}
+			}
+		case "html":
+			htmlOutput = true
+		case "json":
+			jsonOutput = true
+		case "dot":
+			dotPrefix = value
+		case "break":
+			breakpointOptions = append(breakpointOptions, "bk "+value)
+		case "watch":
+			breakpointOptions = append(breakpointOptions, "w "+value)
+		case "history":
+			historyLimit, _ = strconv.Atoi(value)
+		case "cycles":
+			cycleLimit, _ = strconv.Atoi(value)
+		case "platform":
+			platform = value
+		case "listen":
+			listenAddress = value
+		case "moncommands":
+			monitorScript = value
+		case "entry":
+			for _, entry := range strings.Split(value, ",") {
+				parseUint, _ := strconv.ParseUint(entry, 16, 16)
+				entryPoints = append(entryPoints, int(parseUint))
+			}
+		}
+	}
 }
 func opcode() byte {
 	return memory[bytecounter]
@@ -96,6 +322,54 @@ func operand1() byte {
 func operand2() byte {
 	return memory[bytecounter+2]
 }
+// readMemory returns the byte an instruction reads from address. Every data access goes through readMemory and
+// writeMemory so that watchpoints see it, while opcode and operand fetches do not.
+func readMemory(address int) byte {
+	value := memory[address]
+	if watchSet[address] {
+		checkWatchpoints("read", address, value, value)
+	}
+	return value
+}
+// writeMemory stores the byte an instruction writes to address
+func writeMemory(address int, value byte) {
+	old := memory[address]
+	memory[address] = value
+	if recordHistory {
+		recordWrite(address, old)
+	}
+	if watchSet[address] {
+		checkWatchpoints("write", address, value, old)
+	}
+}
+// beginInstruction is called before each opcode switch in execute with the instruction length that switch handles.
+// When the opcode at bytecounter belongs to that switch its label is printed in the trace and it is recorded for the
+// execution guided disassembler. In mon mode, or at a breakpoint, the monitor may stop before it. It reports
+// whether the cycle limit stops the emulator before the instruction.
+func beginInstruction(length int) bool {
+	if monitorActive {
+		monitorInstruction(length)
+	}
+	if opcodes[opcode()].length != length {
+		return false
+	}
+	instructionAddress = bytecounter
+	if recordHistory {
+		recordInstruction()
+	}
+	if monitorActive {
+		trackCall()
+	}
+	if name, ok := labels[bytecounter]; ok && disassemble {
+		fmt.Printf("%s:\n", name)
+	}
+	if executionGuided && recordExecution(bytecounter) {
+		return true
+	}
+	instructionCounter++
+	cycleCount += opcodes[opcode()].cycles
+	return false
+}
 func incCount(amount int) {
 	printMachineState()
 	if bytecounter+amount < len(file)-1 && amount != 0 {
@@ -111,43 +385,13 @@ func getTermDim() (width, height int, err error) {
 	return int(termDim[1]), int(termDim[0]), nil
 }
 func printMachineState() {
-	// Print PC, content of memory at PC, register values and ASCII value of memory all on one line
-	fmt.Printf(";; PC=%04X, A=$%02X X=$%02X Y=$%02X SP=$%04X mem(SP)=$%04X mem(SP+1)=$%04X SR=%08b (NVEBDIZC)\n", PC, A, X, Y, SP, memory[SP], memory[SP+1], SR)
-	// Wait for keypress
-	//fmt.Scanln()
-
-	if machineMonitor {
-		// fmt.Print("\033[H\033[2J") // ANSI escape code hack to clear the screen
-		// Clear the screen once
-		if once {
-			fmt.Printf("\033[2J")
-		}
-		once = false
-		// Move cursor to top left
-		fmt.Printf("\033[0;0H")
-	}
-
-//	if printHex {
-//		fmt.Printf(";; PC=$%04X A=$%02X X=$%02X Y=$%02X SP=$%02X SR=%08b (NVEBDIZC)\n\n", PC, A, X, Y, byte(SP), SR)
-//	}
-
-	if machineMonitor {
-		// Get terminal width and height
-		width, height, _ := getTermDim()
-		fmt.Printf("RAM dump $0000 - $%04X:\n\n", (height-5)*(width/4+6))
-
-		for i := 0; i < height-7; i++ {
-			for j := 0; j < (width/4)+9; j++ {
-				if memory[i*32+j] == 0 {
-					fmt.Printf("\u001B[37m %02X", memory[i*32+j])
-				} else {
-					fmt.Printf("\u001B[3%dm %02X", (memory[i*32+j])%7+1, memory[i*32+j])
-				}
-			}
-			fmt.Printf("\n")
-		}
-		time.Sleep(0 * time.Millisecond)
+	// The execution guided disassembler runs silently and lists the code afterwards, the monitor shows the
+	// registers when it stops
+	if executionGuided || machineMonitor {
+		return
 	}
+	// Print PC, content of memory at PC, register values and ASCII value of memory all on one line
+	fmt.Printf(";; PC=%s, A=$%02X X=$%02X Y=$%02X SP=$%04X mem(SP)=$%04X mem(SP+1)=$%04X SR=%08b (NVEBDIZC)\n", symbolicAddress(PC), A, X, Y, SP, memory[SP], memory[SP+1], SR)
 }
 func consoleOutput() {
 	// Print ASCII character of byte stored at memory[displayAddress]
@@ -225,35 +469,35 @@ func LDA(addressingMode string) {
 		// Get address
 		address := operand1()
 		// Get value from memory at address
-		value := memory[address]
+		value := readMemory(int(address))
 		// Set accumulator to value
 		A = value
 		incCount(2)
 	case ZEROPAGEX: // Zero Page, X
 		// Get address
 		address := operand1() + X
-		value := memory[address]
+		value := readMemory(int(address))
 		// Set accumulator to value
 		A = value
 		incCount(2)
 	case ABSOLUTE: // Absolute
 		// Get 16 bit address from operand 1 and operand 2
 		address := int(operand2())<<8 | int(operand1())
-		value := memory[address]
+		value := readMemory(address)
 		// Set accumulator to value
 		A = value
 		incCount(3)
 	case ABSOLUTEX: // Absolute, X
 		// Get the 16bit X indexed absolute memory address
 		address := int(operand2())<<8 | int(operand1()) + int(X)
-		value := memory[address]
+		value := readMemory(address)
 		// Set accumulator to value
 		A = value
 		incCount(3)
 	case ABSOLUTEY: // Absolute, Y
 		// Get 16 bit address from operand 1 and operand 2
 		address := int(operand2())<<8 | int(operand1()) + int(Y)
-		value := memory[address]
+		value := readMemory(address)
 		// Set accumulator to value
 		A = value
 		incCount(3)
@@ -261,21 +505,21 @@ func LDA(addressingMode string) {
 		// Get the 16bit X indexed zero page indirect address
 		indirectAddress := uint16(int(operand1()) + int(X)&0xFF)
 		// Get the value at the indirect address
-		indirectValue := memory[indirectAddress]
+		indirectValue := readMemory(int(indirectAddress))
 		// Get the value at the indirect address + 1
-		indirectValue2 := memory[(indirectAddress + 1)]
+		indirectValue2 := readMemory(int(indirectAddress + 1))
 		// Combine the two values to get the address
 		indirectAddress = uint16(int(indirectValue) + int(indirectValue2)<<8)
 		// Get the value at the address
-		value := memory[indirectAddress]
+		value := readMemory(int(indirectAddress))
 		// Set the accumulator to the value
 		A = value
 		incCount(2)
 	case INDIRECTY: // Indirect, Y
 		// Get address
-		address := memory[operand1()]
+		address := readMemory(int(operand1()))
 		// Get the value at the address
-		value := memory[address+Y]
+		value := readMemory(int(address+Y))
 		// Set the accumulator to the value
 		A = value
 		incCount(2)
@@ -304,28 +548,28 @@ func LDX(addressingMode string) {
 	case ZEROPAGE: // Zero Page
 		// Get address
 		address := operand1()
-		value := memory[address]
+		value := readMemory(int(address))
 		// Load the value at the address into X
 		X = value
 		incCount(2)
 	case ZEROPAGEY: // Zero Page, Y
 		// Get Y indexed Zero Page address
 		address := operand1() + Y
-		value := memory[address]
+		value := readMemory(int(address))
 		// Load the X register with the Y indexed value in the operand
 		X = value
 		incCount(2)
 	case ABSOLUTE: // Absolute
 		// Get 16 bit address from operands
 		address := uint16(operand2())<<8 | uint16(operand1())
-		value := memory[address]
+		value := readMemory(int(address))
 		// Update X with the value stored at the address in the operands
 		X = value
 		incCount(3)
 	case ABSOLUTEY: // Absolute, Y
 		// Get 16 bit Y indexed address from operands
 		address := int(operand2())<<8 | int(operand1()) + int(Y)
-		value := memory[address]
+		value := readMemory(address)
 		X = value
 		incCount(3)
 	}
@@ -352,28 +596,28 @@ func LDY(addressingMode string) {
 	case ZEROPAGE: // Zero Page
 		// Get address
 		address := operand1()
-		value := memory[address]
+		value := readMemory(int(address))
 		// Load the value at the address into Y
 		Y = value
 		incCount(2)
 	case ZEROPAGEX: // Zero Page, X
 		// Get the X indexed address
 		address := operand1() + X
-		value := memory[address]
+		value := readMemory(int(address))
 		// Load the Y register with the X indexed value in the operand
 		Y = value
 		incCount(2)
 	case ABSOLUTE: // Absolute
 		// Get 16 bit address from operands
 		address := uint16(operand2())<<8 | uint16(operand1())
-		value := memory[address]
+		value := readMemory(int(address))
 		// Update Y with the value stored at the address in the operands
 		Y = value
 		incCount(3)
 	case ABSOLUTEX: // Absolute, X
 		// Get the 16bit X indexed absolute memory address
 		address := int(operand2())<<8 | int(operand1()) + int(X)
-		value := memory[address]
+		value := readMemory(address)
 		// Update Y with the value stored at the address
 		Y = value
 		incCount(3)
@@ -398,48 +642,48 @@ func STA(addressingMode string) {
 		// Get address from operand1()
 		address := operand1()
 		// Store contents of Accumulator in memory
-		memory[address] = A
+		writeMemory(int(address), A)
 		incCount(2)
 	case ZEROPAGEX: // Zero Page, X
 		// Get the X Indexed Zero Page address
 		address := operand1() + X
 		// Store contents of Accumulator in X indexed memory
-		memory[address] = A
+		writeMemory(int(address), A)
 		incCount(2)
 	case ABSOLUTE: // Absolute
 		// Get 16 bit absolute address from operand 1 and operand 2
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Update the memory at the address stored in operand 1 and operand 2 with the value of the accumulator
-		memory[address] = A
+		writeMemory(int(address), A)
 		incCount(3)
 	case ABSOLUTEX: // Absolute, X
 		// Get 16 bit X indexed absolute memory address
 		address := int(operand2())<<8 | int(operand1()) + int(X)
-		memory[address] = A
+		writeMemory(address, A)
 		incCount(3)
 	case ABSOLUTEY: // Absolute, Y
 		// Get 16bit absolute address
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Update the memory at the Y indexed address stored in operand 1 and operand 2 with the value of the accumulator
-		memory[int(address)+int(Y)] = A
+		writeMemory(int(address)+int(Y), A)
 		incCount(3)
 	case INDIRECTX: // Indirect, X
 		// Get the 16bit X indexed zero page indirect address
 		indirectAddress := uint16(int(operand1()) + int(X)&0xFF)
 		// Get the value at the indirect address
-		indirectValue := memory[indirectAddress]
+		indirectValue := readMemory(int(indirectAddress))
 		// Get the value at the indirect address + 1
-		indirectValue2 := memory[(indirectAddress + 1)]
+		indirectValue2 := readMemory(int(indirectAddress + 1))
 		// Combine the two values to get the address
 		indirectAddress = uint16(int(indirectValue) + int(indirectValue2)<<8)
 		// Set the value at the address to the value of A
-		memory[indirectAddress] = A
+		writeMemory(int(indirectAddress), A)
 		incCount(2)
 	case INDIRECTY: // Indirect, Y
 		// Get address
-		address := memory[operand1()]
+		address := readMemory(int(operand1()))
 		// Load accumulator with address+Y index value
-		memory[address+Y] = A
+		writeMemory(int(address+Y), A)
 		incCount(2)
 	}
 	//printMachineState()
@@ -450,19 +694,19 @@ func STX(addressingMode string) {
 		// Get address from operand1()
 		address := operand1()
 		// Store contents of X register in memory address at operand1()
-		memory[address] = X
+		writeMemory(int(address), X)
 		incCount(2)
 	case ZEROPAGEY: // Zero Page, Y
 		// Get Y indexed Zero Page address
 		address := operand1() + Y
 		// Store contents of X register in Y indexed memory address
-		memory[address] = X
+		writeMemory(int(address), X)
 		incCount(2)
 	case ABSOLUTE: // Absolute
 		// Get the 16 bit address from operand 1 and operand 2
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Update the memory at the address stored in operand 1 and operand 2 with the value of the X register
-		memory[address] = X
+		writeMemory(int(address), X)
 		incCount(3)
 	}
 	//printMachineState()
@@ -473,19 +717,19 @@ func STY(addressingMode string) {
 		// Get address
 		address := operand1()
 		// Store Y register in memory at address in operand1()
-		memory[address] = Y
+		writeMemory(int(address), Y)
 		incCount(2)
 	case ZEROPAGEX: // Zero Page, X
 		// Get X indexed Zero Page address
 		address := operand1() + X
 		// Store contents of Y register in X indexed memory address
-		memory[address] = Y
+		writeMemory(int(address), Y)
 		incCount(2)
 	case ABSOLUTE: // Absolute
 		// Get the 16 bit address from operands
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Update the memory at the address stored in operand 1 and operand 2 with the value of the Y register
-		memory[address] = Y
+		writeMemory(int(address), Y)
 		incCount(3)
 	}
 	//printMachineState()
@@ -500,37 +744,37 @@ func CMP(addressingMode string) {
 		// Get address
 		address := operand1()
 		// Subtract the operand from the accumulator
-		value = memory[address]
+		value = readMemory(int(address))
 	case ZEROPAGEX: // Zero Page, X
 		// Get address
 		address := operand1() + X
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 	case ABSOLUTE: // Absolute
 		// Get 16bit absolute address
 		address := int(operand2())<<8 | int(operand1())
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(address)
 	case ABSOLUTEX: // Absolute, X
 		// Get address
 		address := uint16(operand2())<<8 | uint16(operand1()) + uint16(X)
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 	case ABSOLUTEY: // Absolute, Y
 		// Get address
 		address := int(operand2())<<8 | int(operand1()) + int(Y)
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(address)
 	case INDIRECTX: // Indirect, X
 		// Get the address of the operand
 		address := int(operand1()) + int(X)
 		// Get the value of the operand
-		value = memory[address]
+		value = readMemory(address)
 	case INDIRECTY: // Indirect, Y
 		// Get address from operand1() and add Y to it
-		address := memory[operand1()] + Y
+		address := readMemory(int(operand1())) + Y
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 	}
 	// Subtract the value from the accumulator
 	result = A - value
@@ -571,7 +815,7 @@ func JMP(addressingMode string) {
 		// Get the 16 bit address from operands
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get the indirect address
-		indirectAddress := uint16(memory[address+1])<<8 | uint16(memory[address])
+		indirectAddress := uint16(readMemory(int(address+1)))<<8 | uint16(readMemory(int(address)))
 		// Set the program counter to the indirect address
 		PC = int(indirectAddress)
 	}
@@ -594,7 +838,7 @@ func AND(addressingMode string) {
 		// Get the address from the operand
 		address := operand1()
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// AND the value with the accumulator
 		result = A & value
 		// Set the accumulator to the result
@@ -604,7 +848,7 @@ func AND(addressingMode string) {
 		// Get address
 		address := operand1() + X
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// AND the value with the accumulator
 		result = A & value
 		// Set the accumulator to the result
@@ -614,7 +858,7 @@ func AND(addressingMode string) {
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// AND the value with the accumulator
 		result = A & value
 		// Set the accumulator to the result
@@ -624,7 +868,7 @@ func AND(addressingMode string) {
 		// Get address
 		address := uint16(operand2())<<8 | uint16(operand1()) + uint16(X)
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// AND the value with the accumulator
 		result = A & value
 		// Set the accumulator to the result
@@ -634,7 +878,7 @@ func AND(addressingMode string) {
 		// Get the address
 		address := int(operand2())<<8 | int(operand1()) + int(Y)
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(address)
 		// AND the value with the accumulator
 		result = A & value
 		// Set the accumulator to the result
@@ -643,9 +887,9 @@ func AND(addressingMode string) {
 	case INDIRECTX:
 		// Get the address
 		indirectAddress := int(operand1()) + int(X)
-		address := int(memory[indirectAddress]) + int(memory[indirectAddress+1])<<8
+		address := int(readMemory(indirectAddress)) + int(readMemory(indirectAddress+1))<<8
 		// Get the value from the address
-		value = memory[address]
+		value = readMemory(address)
 		// AND the value with the accumulator
 		result = A & value
 		// Set the accumulator to the result
@@ -655,11 +899,11 @@ func AND(addressingMode string) {
 		// Get the 16bit address
 		address := uint16(int(operand1()))
 		// Get the indirect address
-		indirectAddress1 := memory[address]
-		indirectAddress2 := memory[address+1]
+		indirectAddress1 := readMemory(int(address))
+		indirectAddress2 := readMemory(int(address+1))
 		indirectAddress := uint16(int(indirectAddress1)+int(indirectAddress2)<<8) + uint16(Y)
 		// Get the value at the address
-		value = memory[indirectAddress]
+		value = readMemory(int(indirectAddress))
 		// AND the value with the accumulator
 		result = A & value
 		// Set the accumulator to the result
@@ -695,7 +939,7 @@ func EOR(addressingMode string) {
 		// Get the address from the operand
 		address := operand1()
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// XOR the value with the accumulator
 		result = A ^ value
 		// Set the accumulator to the result
@@ -705,7 +949,7 @@ func EOR(addressingMode string) {
 		// Get address
 		address := operand1() + X
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// XOR the value with the accumulator
 		result = A ^ value
 		// Set the accumulator to the result
@@ -715,7 +959,7 @@ func EOR(addressingMode string) {
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// XOR the value with the accumulator
 		result = A ^ value
 		// Set the accumulator to the result
@@ -725,7 +969,7 @@ func EOR(addressingMode string) {
 		// Get address
 		address := uint16(operand2())<<8 | uint16(operand1()) + uint16(X)
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// XOR the value with the accumulator
 		result = A ^ value
 		// Set the accumulator to the result
@@ -735,7 +979,7 @@ func EOR(addressingMode string) {
 		// Get the address
 		address := int(operand2())<<8 | int(operand1()) + int(Y)
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(address)
 		// XOR the value with the accumulator
 		result = A ^ value
 		// Set the accumulator to the result
@@ -744,9 +988,9 @@ func EOR(addressingMode string) {
 	case INDIRECTX:
 		// Get the address
 		indirectAddress := int(operand1()) + int(X)
-		address := int(memory[indirectAddress]) + int(memory[indirectAddress+1])<<8
+		address := int(readMemory(indirectAddress)) + int(readMemory(indirectAddress+1))<<8
 		// Get the value from the address
-		value = memory[address]
+		value = readMemory(address)
 		// XOR the value with the accumulator
 		result = A ^ value
 		// Set the accumulator to the result
@@ -756,11 +1000,11 @@ func EOR(addressingMode string) {
 		// Get the 16bit address
 		address := uint16(int(operand1()))
 		// Get the indirect address
-		indirectAddress1 := memory[address]
-		indirectAddress2 := memory[address+1]
+		indirectAddress1 := readMemory(int(address))
+		indirectAddress2 := readMemory(int(address+1))
 		indirectAddress := uint16(int(indirectAddress1)+int(indirectAddress2)<<8) + uint16(Y)
 		// Get the value at the address
-		value = memory[indirectAddress]
+		value = readMemory(int(indirectAddress))
 		// XOR the value with the accumulator
 		result = A ^ value
 		// Set the accumulator to the result
@@ -796,7 +1040,7 @@ func ORA(addressingMode string) {
 		// Get the address from the operand
 		address := operand1()
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// OR the value with the accumulator
 		result = A | value
 		// Set the accumulator to the result
@@ -806,7 +1050,7 @@ func ORA(addressingMode string) {
 		// Get address
 		address := operand1() + X
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// OR the value with the accumulator
 		result = A | value
 		// Set the accumulator to the result
@@ -816,7 +1060,7 @@ func ORA(addressingMode string) {
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// OR the value with the accumulator
 		result = A | value
 		// Set the accumulator to the result
@@ -826,7 +1070,7 @@ func ORA(addressingMode string) {
 		// Get address
 		address := uint16(operand2())<<8 | uint16(operand1()) + uint16(X)
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// OR the value with the accumulator
 		result = A | value
 		// Set the accumulator to the result
@@ -836,7 +1080,7 @@ func ORA(addressingMode string) {
 		// Get the address
 		address := int(operand2())<<8 | int(operand1()) + int(Y)
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(address)
 		// OR the value with the accumulator
 		result = A | value
 		// Set the accumulator to the result
@@ -845,9 +1089,9 @@ func ORA(addressingMode string) {
 	case INDIRECTX:
 		// Get the address
 		indirectAddress := int(operand1()) + int(X)
-		address := int(memory[indirectAddress]) + int(memory[indirectAddress+1])<<8
+		address := int(readMemory(indirectAddress)) + int(readMemory(indirectAddress+1))<<8
 		// Get the value from the address
-		value = memory[address]
+		value = readMemory(address)
 		// OR the value with the accumulator
 		result = A | value
 		// Set the accumulator to the result
@@ -857,11 +1101,11 @@ func ORA(addressingMode string) {
 		// Get the 16bit address
 		address := uint16(int(operand1()))
 		// Get the indirect address
-		indirectAddress1 := memory[address]
-		indirectAddress2 := memory[address+1]
+		indirectAddress1 := readMemory(int(address))
+		indirectAddress2 := readMemory(int(address+1))
 		indirectAddress := uint16(int(indirectAddress1)+int(indirectAddress2)<<8) + uint16(Y)
 		// Get the value at the address
-		value = memory[indirectAddress]
+		value = readMemory(int(indirectAddress))
 		// OR the value with the accumulator
 		result = A | value
 		// Set the accumulator to the result
@@ -892,7 +1136,7 @@ func BIT(addressingMode string) {
 		// Get the address from the operand
 		address := operand1()
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// AND the value with the accumulator
 		result = A & value
 		incCount(2)
@@ -900,7 +1144,7 @@ func BIT(addressingMode string) {
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// AND the value with the accumulator
 		result = A & value
 		incCount(3)
@@ -930,41 +1174,41 @@ func INC(addressingMode string) {
 		// Get the address from the operand
 		address := operand1()
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Increment the value
 		result = value + 1
 		// Set the value at the address to the result
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(2)
 	case ZEROPAGEX:
 		// Get the address from the operand
 		address := operand1() + X
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Increment the value
 		result = value + 1
 		// Set the value at the address to the result
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(2)
 	case ABSOLUTE:
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Increment the value
 		result = value + 1
 		// Set the value at the address to the result
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(3)
 	case ABSOLUTEX:
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1()) + uint16(X)
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Increment the value
 		result = value + 1
 		// Set the value at the address to the result
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(3)
 	}
 	// If bit 7 of the result is set, set the negative flag
@@ -988,41 +1232,41 @@ func DEC(addressingMode string) {
 		// Get the address from the operand
 		address := operand1()
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Decrement the value
 		result = value - 1
 		// Set the value at the address to the result
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(2)
 	case ZEROPAGEX:
 		// Get the address from the operand
 		address := operand1() + X
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Decrement the value
 		result = value - 1
 		// Set the value at the address to the result
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(2)
 	case ABSOLUTE:
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Decrement the value
 		result = value - 1
 		// Set the value at the address to the result
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(3)
 	case ABSOLUTEX:
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1()) + uint16(X)
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Decrement the value
 		result = value - 1
 		// Set the value at the address to the result
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(3)
 	}
 	// If bit 7 of the result is set, set the negative flag
@@ -1050,41 +1294,41 @@ func ADC(addressingMode string) {
 		// Get the address from the operand
 		address := operand1()
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 	case ZEROPAGEX:
 		// Get the address from the operand
 		address := operand1() + X
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 	case ABSOLUTE:
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 	case ABSOLUTEX:
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1()) + uint16(X)
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 	case ABSOLUTEY:
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1()) + uint16(Y)
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 	case INDIRECTX:
 		// Get the indirect address from the operand
 		indirectAddress := operand1() + X
 		// Get the address from the indirect address
-		address := uint16(memory[indirectAddress+1])<<8 | uint16(memory[indirectAddress])
+		address := uint16(readMemory(int(indirectAddress+1)))<<8 | uint16(readMemory(int(indirectAddress)))
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 	case INDIRECTY:
 		// Get the indirect address from the operand
 		indirectAddress := operand1()
 		// Get the address from the indirect address
-		address := uint16(memory[indirectAddress+1])<<8 | uint16(memory[indirectAddress]) + uint16(Y)
+		address := uint16(readMemory(int(indirectAddress+1)))<<8 | uint16(readMemory(int(indirectAddress))) + uint16(Y)
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 	}
 	/*
 		This instruction adds the value of memory and carry from the previous operation to the value of the accumulator
@@ -1157,41 +1401,41 @@ func SBC(addressingMode string) {
 		// Get the address from the operand
 		address := operand1()
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 	case ZEROPAGEX:
 		// Get the address from the operand
 		address := operand1() + X
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 	case ABSOLUTE:
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 	case ABSOLUTEX:
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1()) + uint16(X)
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 	case ABSOLUTEY:
 		// Get 16 bit address from operand1 and operand2
 		address := uint16(operand2())<<8 | uint16(operand1()) + uint16(Y)
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 	case INDIRECTX:
 		// Get the indirect address from the operand
 		indirectAddress := operand1() + X
 		// Get the address from the indirect address
-		address := uint16(memory[indirectAddress+1])<<8 | uint16(memory[indirectAddress])
+		address := uint16(readMemory(int(indirectAddress+1)))<<8 | uint16(readMemory(int(indirectAddress)))
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 	case INDIRECTY:
 		// Get the indirect address from the operand
 		indirectAddress := operand1()
 		// Get the address from the indirect address
-		address := uint16(memory[indirectAddress+1])<<8 | uint16(memory[indirectAddress]) + uint16(Y)
+		address := uint16(readMemory(int(indirectAddress+1)))<<8 | uint16(readMemory(int(indirectAddress))) + uint16(Y)
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 	}
 	// Subtract the value from the accumulator with borrow
 	result = int(A) - int(value)
@@ -1245,28 +1489,28 @@ func ROR(addressingMode string) {
 		// Get address
 		address = operand1()
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Shift the value right 1 bit
 		result = value >> 1
 	case ZEROPAGEX:
 		// Get X indexed zero page address
 		address = operand1() + X
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Shift the value right 1 bit
 		result = value >> 1
 	case ABSOLUTE:
 		// Get 16 bit address from operands
 		address16 = uint16(operand2())<<8 | uint16(operand1())
 		// Get the value stored at the address in the operands
-		value = memory[address16]
+		value = readMemory(int(address16))
 		// Shift the value right 1 bit
 		result = value >> 1
 	case ABSOLUTEX:
 		// Get 16 bit address
 		address16 = uint16(operand2())<<8 | uint16(operand1()) + uint16(X)
 		// Get value stored at address
-		value = memory[address16]
+		value = readMemory(int(address16))
 		// Shift right the value by 1 bit
 		result = value >> 1
 	}
@@ -1296,12 +1540,12 @@ func ROR(addressingMode string) {
 	}
 	if addressingMode == ZEROPAGE || addressingMode == ZEROPAGEX {
 		// Store the value back into memory
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(2)
 	}
 	if addressingMode == ABSOLUTE || addressingMode == ABSOLUTEX {
 		// Store the value back into memory
-		memory[address16] = result
+		writeMemory(int(address16), result)
 		incCount(3)
 	}
 	//printMachineState()
@@ -1321,7 +1565,7 @@ func ROL(addressingMode string) {
 		// Get address
 		address = operand1()
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Shift the value left 1 bit
 		result = value << 1
 		// Update bit 0 of result with the value of the carry flag
@@ -1330,7 +1574,7 @@ func ROL(addressingMode string) {
 		// Get X indexed zero page address
 		address = operand1() + X
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Shift the value left 1 bit
 		result = value << 1
 		// Update bit 0 of result with the value of the carry flag
@@ -1339,7 +1583,7 @@ func ROL(addressingMode string) {
 		// Get 16 bit address from operands
 		address16 = uint16(operand2())<<8 | uint16(operand1())
 		// Get the value stored at the address in the operands
-		value = memory[address16]
+		value = readMemory(int(address16))
 		// Shift the value left 1 bit
 		result = value << 1
 		// Update bit 0 of result with the value of the carry flag
@@ -1348,7 +1592,7 @@ func ROL(addressingMode string) {
 		// Get 16bit X indexed absolute memory address
 		address16 = uint16(operand2())<<8 | uint16(operand1()) + uint16(X)
 		// Get the value stored at the address
-		value = memory[address16]
+		value = readMemory(int(address16))
 		// Shift the value left 1 bit
 		result = value << 1
 		// Update bit 0 of result with the value of the carry flag
@@ -1379,12 +1623,12 @@ func ROL(addressingMode string) {
 	}
 	if addressingMode == ZEROPAGE || addressingMode == ZEROPAGEX {
 		// Store the value back into memory
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(2)
 	}
 	if addressingMode == ABSOLUTE || addressingMode == ABSOLUTEX {
 		// Store the value back into memory
-		memory[address16] = result
+		writeMemory(int(address16), result)
 		incCount(3)
 	}
 	//printMachineState()
@@ -1404,41 +1648,41 @@ func LSR(addressingMode string) {
 		// Get address
 		address := operand1()
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Shift the value right 1 bit
 		value >>= 1
 		// Store the value back into memory
-		memory[address] = value
+		writeMemory(int(address), value)
 		incCount(2)
 	case ZEROPAGEX:
 		// Get the X indexed address
 		address := operand1() + X
 		// Get the value at the X indexed address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Shift the value right 1 bit
 		value >>= 1
 		// Store the shifted value in memory
-		memory[address] = value
+		writeMemory(int(address), value)
 		incCount(2)
 	case ABSOLUTE:
 		// Get 16 bit address from operands
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get the value stored at the address in the operands
-		value = memory[address]
+		value = readMemory(int(address))
 		// Shift the value right 1 bit
 		value >>= 1
 		// Store the shifted value back in memory
-		memory[address] = value
+		writeMemory(int(address), value)
 		incCount(3)
 	case ABSOLUTEX:
 		// Get the 16bit X indexed absolute memory address
 		address := int(operand2())<<8 | int(operand1()) + int(X)
 		// Get the value stored at the address
-		value = memory[address]
+		value = readMemory(address)
 		// Shift the value right 1 bit
 		value >>= 1
 		// Store the shifted value back in memory
-		memory[address] = value
+		writeMemory(address, value)
 		incCount(3)
 	}
 	// Reset the SR negative flag
@@ -1472,41 +1716,41 @@ func ASL(addressingMode string) {
 		// Get address
 		address := operand1()
 		// Get the value at the address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Shift the value left 1 bit
 		result = value << 1
 		// Store the value back into memory
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(2)
 	case ZEROPAGEX:
 		// Get the X indexed address
 		address := operand1() + X
 		// Get the value at the X indexed address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Shift the value left 1 bit
 		result = value << 1
 		// Store the shifted value in memory
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(2)
 	case ABSOLUTE:
 		// Get 16 bit address from operands
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get the value stored at the address in the operands
-		value = memory[address]
+		value = readMemory(int(address))
 		// Shift the value left 1 bit
 		result = value << 1
 		// Store the shifted value back in memory
-		memory[address] = result
+		writeMemory(int(address), result)
 		incCount(3)
 	case ABSOLUTEX:
 		// Get the 16bit X indexed absolute memory address
 		address := int(operand2())<<8 | int(operand1()) + int(X)
 		// Get the value stored at the address
-		value = memory[address]
+		value = readMemory(address)
 		// Shift the value left 1 bit
 		result = value << 1
 		// Store the shifted value back in memory
-		memory[address] = result
+		writeMemory(address, result)
 		incCount(3)
 	}
 	// Set the SR Negative flag to the bit 7 of the result
@@ -1542,7 +1786,7 @@ func CPX(addressingMode string) {
 		// Get address
 		address := operand1()
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Store result of X-memory stored at operand1() in result variable
 		result = X - value
 		incCount(2)
@@ -1550,7 +1794,7 @@ func CPX(addressingMode string) {
 		// Get address
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		incCount(3)
 	}
 	// If X >= value then set carry flag bit 0 to 1 set carry flag bit 0 to 0
@@ -1590,7 +1834,7 @@ func CPY(addressingMode string) {
 		// Get address
 		address := operand1()
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		// Store result of Y-memory stored at operand1() in result variable
 		result = Y - value
 		incCount(2)
@@ -1598,7 +1842,7 @@ func CPY(addressingMode string) {
 		// Get address
 		address := uint16(operand2())<<8 | uint16(operand1())
 		// Get value at address
-		value = memory[address]
+		value = readMemory(int(address))
 		incCount(3)
 	}
 	// If Y>value then set carry flag to 1 else set carry flag to 0
@@ -1630,8 +1874,11 @@ func execute() {
 	if disassemble {
 		fmt.Printf(" *= $%04X\n\n", PC)
 	}
-	for bytecounter = PC; PC < len(memory); instructionCounter++ {
+	for bytecounter = PC; PC < len(memory); {
 		//consoleOutput()
+		if beginInstruction(1) {
+			break
+		}
 		//  1 byte instructions with no operands
 		switch opcode() {
 		// Implied addressing mode instructions
@@ -1664,13 +1911,13 @@ func execute() {
 
 			SP--
 			//  Push PC onto stack
-			memory[SP] = byte(PC >> 8)
+			writeMemory(int(SP), byte(PC >> 8))
 			SP--
 			// Store SR on stack
-			memory[SP] = SR
+			writeMemory(int(SP), SR)
 			SP--
 			// Set PC low byte to memory[0xFFFE] and high byte to memory[0xFFFF]
-			PC = int(uint16(memory[0xFFFF])<<8 | uint16(memory[0xFFFE]))
+			PC = int(uint16(readMemory(0xFFFF))<<8 | uint16(readMemory(0xFFFE)))
 			bytecounter = PC
 
 			// Set SR interrupt disable bit to 1
@@ -1939,7 +2186,7 @@ func execute() {
 			}
 
 			// Update memory address pointed to by SP with value stored in accumulator
-			memory[SP] = A
+			writeMemory(int(SP), A)
 			// Decrement the stack pointer by 1 byte
 			SP--
 			incCount(1)
@@ -1961,7 +2208,7 @@ func execute() {
 			}
 
 			// Push SR to stack
-			memory[SP] = SR
+			writeMemory(int(SP), SR)
 			// Decrement the stack pointer by 1 byte
 			SP--
 			incCount(1)
@@ -1990,7 +2237,7 @@ func execute() {
 			// Increment the stack pointer by 1 byte
 			SP++
 			// Update accumulator with value stored in memory address pointed to by SP
-			A = memory[SP]
+			A = readMemory(int(SP))
 			// If bit 7 of accumulator is set, set negative SR flag else set negative SR flag to 0
 			if getABit(7) == 1 {
 				setNegativeFlag()
@@ -2025,7 +2272,7 @@ func execute() {
 			// Increment the stack pointer by 1 byte
 			SP++
 			// Update SR with the value stored at the address pointed to by SP
-			SR = memory[SP]
+			SR = readMemory(int(SP))
 			incCount(1)
 		case 0x40:
 			/*
@@ -2053,15 +2300,15 @@ func execute() {
 			// Increment the stack pointer by 1 byte
 			SP++
 			//Update SR with the value stored in memory at the address pointed to by SP
-			SR = memory[SP]
+			SR = readMemory(int(SP))
 			// Increment the stack pointer by 1 byte
 			SP++
 			//Get low byte of PC
-			low := uint16(memory[SP])
+			low := uint16(readMemory(int(SP)))
 			// Increment the stack pointer by 1 byte
 			SP++
 			//Get high byte of PC
-			high := uint16(memory[SP])
+			high := uint16(readMemory(int(SP)))
 			//Update PC with the value stored in memory at the address pointed to by SP
 			PC = int((high << 8) | low)
 			bytecounter = PC
@@ -2087,11 +2334,11 @@ func execute() {
 			// Increment the stack pointer by 1 byte
 			SP++
 			//Get low byte of PC
-			low := uint16(memory[SP])
+			low := uint16(readMemory(int(SP)))
 			// Increment the stack pointer by 1 byte
 			SP++
 			//Get high byte of PC
-			high := uint16(memory[SP])
+			high := uint16(readMemory(int(SP)))
 			//Update PC with the value stored in memory at the address pointed to by SP
 			PC = int((high << 8) | low)
 			bytecounter = PC
@@ -2452,6 +2699,9 @@ func execute() {
 		}
 
 		// 2 byte instructions with 1 operand
+		if beginInstruction(2) {
+			break
+		}
 		switch opcode() {
 		// Immediate addressing mode instructions
 		/*
@@ -2483,7 +2733,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Immediate)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ADC #$%02X\n", operand1())
+				fmt.Printf("ADC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			ADC("immediate")
@@ -2503,7 +2753,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Immediate)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("AND #$%02X\n", operand1())
+				fmt.Printf("AND %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			AND("immediate")
@@ -2525,7 +2775,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Immediate)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("CMP #$%02X\n", operand1())
+				fmt.Printf("CMP %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			CMP("immediate")
@@ -2550,7 +2800,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Immediate)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("CPX #$%02X\n", operand1())
+				fmt.Printf("CPX %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			CPX("immediate")
@@ -2577,7 +2827,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Immediate)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("CPY #$%02X\n", operand1())
+				fmt.Printf("CPY %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			CPY("immediate")
@@ -2597,7 +2847,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Immediate)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("EOR #$%02X\n", operand1())
+				fmt.Printf("EOR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			EOR("immediate")
@@ -2617,7 +2867,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Immediate)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LDA #$%02X\n", operand1())
+				fmt.Printf("LDA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LDA("immediate")
 		case 0xA2:
@@ -2634,7 +2884,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Immediate)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LDX #$%02X\n", operand1())
+				fmt.Printf("LDX %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LDX("immediate")
 		case 0xA0:
@@ -2652,7 +2902,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Immediate)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LDY #$%02X\n", operand1())
+				fmt.Printf("LDY %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			LDY("immediate")
@@ -2672,7 +2922,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Immediate)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ORA #$%02x\n", operand1())
+				fmt.Printf("ORA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			ORA("immediate")
@@ -2698,7 +2948,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Immediate)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("SBC #$%02X\n", operand1())
+				fmt.Printf("SBC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			SBC("immediate")
 
@@ -2735,7 +2985,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ADC $%02X\n", operand1())
+				fmt.Printf("ADC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			ADC("zeropage")
@@ -2755,7 +3005,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("AND $%02X\n", operand1())
+				fmt.Printf("AND %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			AND("zeropage")
@@ -2777,7 +3027,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ASL $%02x\n", operand1())
+				fmt.Printf("ASL %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			ASL("zeropage")
@@ -2799,7 +3049,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("BIT $%02X\n", operand1())
+				fmt.Printf("BIT %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			BIT("zeropage")
@@ -2820,7 +3070,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("CMP $%02X\n", operand1())
+				fmt.Printf("CMP %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			CMP("zeropage")
 		case 0xE4:
@@ -2846,7 +3096,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("CPX $%02X\n", operand1())
+				fmt.Printf("CPX %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			CPX("zeropage")
@@ -2871,7 +3121,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("CPY $%02X\n", operand1())
+				fmt.Printf("CPY %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			CPY("zeropage")
 		case 0xC6:
@@ -2891,7 +3141,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("DEC $%02X\n", operand1())
+				fmt.Printf("DEC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			DEC("zeropage")
@@ -2911,7 +3161,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("EOR $%02X\n", operand1())
+				fmt.Printf("EOR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			EOR("zeropage")
@@ -2931,7 +3181,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("INC $%02X\n", operand1())
+				fmt.Printf("INC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			INC("zeropage")
@@ -2951,7 +3201,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LDA $%02X\n", operand1())
+				fmt.Printf("LDA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			LDA("zeropage")
@@ -2970,7 +3220,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LDX $%02X\n", operand1())
+				fmt.Printf("LDX %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LDX("zeropage")
 		case 0xA4:
@@ -2988,7 +3238,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LDY $%02X\n", operand1())
+				fmt.Printf("LDY %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			LDY("zeropage")
@@ -3013,7 +3263,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LSR $%02X\n", operand1())
+				fmt.Printf("LSR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			LSR("zeropage")
@@ -3034,7 +3284,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ORA $%02x\n", operand1())
+				fmt.Printf("ORA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			ORA("zeropage")
@@ -3057,7 +3307,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ROL $%02X\n", operand1())
+				fmt.Printf("ROL %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			ROL("zeropage")
@@ -3082,7 +3332,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ROR $%02X\n", operand1())
+				fmt.Printf("ROR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			ROR("zeropage")
@@ -3107,7 +3357,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("SBC $%02X\n", operand1())
+				fmt.Printf("SBC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			SBC("zeropage")
@@ -3126,7 +3376,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("STA $%02X\n", operand1())
+				fmt.Printf("STA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			STA("zeropage")
@@ -3143,7 +3393,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("STX $%02X\n", operand1())
+				fmt.Printf("STX %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			STX("zeropage")
 		case 0x84:
@@ -3159,7 +3409,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page)\t\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("STY $%02X\n", operand1())
+				fmt.Printf("STY %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			STY("zeropage")
@@ -3191,7 +3441,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ADC $%02X,X\n", operand1())
+				fmt.Printf("ADC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			ADC("zeropagex")
@@ -3211,7 +3461,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t(Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("AND $%02X,X\n", operand1())
+				fmt.Printf("AND %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			AND("zeropagex")
@@ -3232,7 +3482,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(ASL - Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ASL $%02X,X\n", operand1())
+				fmt.Printf("ASL %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			ASL("zeropagex")
@@ -3254,7 +3504,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("CMP $%02X,X\n", operand1())
+				fmt.Printf("CMP %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			CMP("zeropagex")
@@ -3274,7 +3524,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("DEC $%02X,X\n", operand1())
+				fmt.Printf("DEC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			DEC("zeropagex")
@@ -3294,7 +3544,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LDA $%02X,X\n", operand1())
+				fmt.Printf("LDA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			LDA("zeropagex")
@@ -3312,7 +3562,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LDY $%02X,X\n", operand1())
+				fmt.Printf("LDY %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			LDY("zeropagex")
@@ -3337,7 +3587,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(X Zero Page)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LSR $%02X,X\n", operand1())
+				fmt.Printf("LSR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			LSR("zeropagex")
@@ -3357,7 +3607,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ORA $%02x,X\n", operand1())
+				fmt.Printf("ORA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			ORA("zeropagex")
@@ -3380,7 +3630,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(X Zero Page)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ROL $%02X,X\n", operand1())
+				fmt.Printf("ROL %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ROL("zeropagex")
 		case 0x76:
@@ -3404,7 +3654,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ROR $%02X,X\n", operand1())
+				fmt.Printf("ROR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ROR("zeropagex")
 		case 0xF5:
@@ -3428,7 +3678,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("SBC $%02X,X\n", operand1())
+				fmt.Printf("SBC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			SBC("zeropagex")
 		case 0x95:
@@ -3445,7 +3695,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("STA $%02X,X\n", operand1())
+				fmt.Printf("STA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			STA("zeropagex")
 		case 0x94:
@@ -3461,7 +3711,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("STY $%02X,X\n", operand1())
+				fmt.Printf("STY %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			STY("zeropagex")
@@ -3489,7 +3739,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,Y)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LDX $%02X,Y\n", operand1())
+				fmt.Printf("LDX %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LDX("zeropagey")
 		case 0x96:
@@ -3505,7 +3755,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,Y)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("STX $%02X,Y\n", operand1())
+				fmt.Printf("STX %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			STX("zeropagey")
@@ -3538,7 +3788,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(X Zero Page Indirect)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ADC ($%02X,X)\n", operand1())
+				fmt.Printf("ADC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ADC("indirectx")
 		case 0x21:
@@ -3557,7 +3807,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t((X Zero Page Indirect))\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("AND ($%02X,X)\n", operand1())
+				fmt.Printf("AND %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			AND("indirectx")
@@ -3580,7 +3830,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(X Zero Page Indirect)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("CMP ($%02X,X)\n", operand1())
+				fmt.Printf("CMP %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			CMP("indirectx")
@@ -3600,7 +3850,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t((X Zero Page, Indirect))\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("EOR ($%02X,X)\n", operand1())
+				fmt.Printf("EOR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			EOR("indirectx")
@@ -3620,7 +3870,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(X Zero Page Indirect)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LDA ($%02X,X)\n", operand1())
+				fmt.Printf("LDA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			LDA("indirectx")
@@ -3640,7 +3890,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t((X Zero Page Indirect))\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ORA ($%02x,X)\n", operand1())
+				fmt.Printf("ORA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			ORA("indirectx")
@@ -3665,7 +3915,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(X Zero Page Indirect)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("SBC ($%02X,X)\n", operand1())
+				fmt.Printf("SBC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			SBC("indirectx")
@@ -3683,7 +3933,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(X Zero Page Indirect)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("STA ($%02X,X)\n", operand1())
+				fmt.Printf("STA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			STA("indirectx")
 
@@ -3715,7 +3965,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t((Zero Page Indirect),Y)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ADC ($%02X),Y\n", operand1())
+				fmt.Printf("ADC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			ADC("indirecty")
@@ -3735,7 +3985,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t((Zero Page Indirect),Y)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("AND ($%02X),Y\n", operand1())
+				fmt.Printf("AND %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			AND("indirecty")
@@ -3756,7 +4006,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t((Zero Page Indirect),Y)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("CMP ($%02X),Y\n", operand1())
+				fmt.Printf("CMP %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			CMP("indirecty")
@@ -3776,7 +4026,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t((Zero Page Indirect),Y)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("EOR ($%02X),Y\n", operand1())
+				fmt.Printf("EOR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			EOR("indirecty")
@@ -3796,7 +4046,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t((Zero Page Indirect),Y)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("LDA ($%02X),Y\n", operand1())
+				fmt.Printf("LDA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			LDA("indirecty")
@@ -3816,7 +4066,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t((Zero Page),Indirect Y)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("ORA ($%02x),Y\n", operand1())
+				fmt.Printf("ORA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ORA("indirecty")
 
@@ -3841,7 +4091,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t((Zero Page Indirect),Y)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("SBC ($%02X),Y\n", operand1())
+				fmt.Printf("SBC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			SBC("indirecty")
@@ -3858,7 +4108,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t((Zero Page Indirect),Y)\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("STA ($%02X),Y\n", operand1())
+				fmt.Printf("STA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			STA("indirecty")
@@ -3900,7 +4150,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Relative)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("BPL $%02X\n", (bytecounter+2+int(operand1()))&0xFF)
+				fmt.Printf("BPL %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			// Get offset from operand
@@ -3935,7 +4185,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Relative)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("BMI $%02X\n", (bytecounter+2+int(operand1()))&0xFF)
+				fmt.Printf("BMI %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			// Get offset from operand
@@ -3970,7 +4220,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Relative)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("BVC $%02X\n", bytecounter+2+int(operand1()))
+				fmt.Printf("BVC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			// Get offset from operand
@@ -4007,7 +4257,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(X Zero Page)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("EOR $%02X,X\n", operand1())
+				fmt.Printf("EOR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			EOR("zeropagex")
@@ -4025,7 +4275,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Relative)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("BVS $%04X\n", bytecounter+2+int(operand1()))
+				fmt.Printf("BVS %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			// Get offset from operand
@@ -4060,7 +4310,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Relative)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("BCC $%02X\n", (bytecounter+2+int(operand1()))&0xFF)
+				fmt.Printf("BCC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			// Get offset from operand
@@ -4095,7 +4345,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Relative)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("BCS $%02X\n", (bytecounter+2+int(operand1()))&0xFF)
+				fmt.Printf("BCS %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			// Get offset from operand
 			offset := operand1()
@@ -4132,7 +4382,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Relative)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("BNE $%04X\n", (bytecounter+2+int(operand1()))&0xFF)
+				fmt.Printf("BNE %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			// Get offset from operand
@@ -4169,7 +4419,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Relative)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("BEQ $%04X\n", bytecounter+2+int(operand1()))
+				fmt.Printf("BEQ %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			// Get offset from address in operand
@@ -4219,13 +4469,16 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x\t\t(Zero Page,X)\t\n", PC, opcode(), operand1())
 				}
-				fmt.Printf("INC $%02X,X\n", operand1())
+				fmt.Printf("INC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			INC("zeropagex")
 		}
 
 		// 3 byte instructions with 2 operands
+		if beginInstruction(3) {
+			break
+		}
 		switch opcode() {
 		// Absolute addressing mode instructions
 		/*
@@ -4257,7 +4510,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("ADC $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("ADC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ADC("absolute")
 		case 0x2D:
@@ -4276,7 +4529,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("AND $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("AND %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			AND("absolute")
@@ -4300,7 +4553,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("ASL $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("ASL %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ASL("absolute")
 		case 0x2C:
@@ -4323,7 +4576,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("BIT $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("BIT %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			BIT("absolute")
 		case 0xCD:
@@ -4344,7 +4597,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("CMP $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("CMP %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			CMP("absolute")
 		case 0xEC:
@@ -4370,7 +4623,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("CPX $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("CPX %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			CPX("absolute")
 		case 0xCC:
@@ -4395,7 +4648,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("CPY $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("CPY %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			CPY("absolute")
 		case 0xCE:
@@ -4414,7 +4667,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("DEC $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("DEC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			DEC("absolute")
 		case 0x4D:
@@ -4433,7 +4686,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("EOR $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("EOR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			EOR("absolute")
 		case 0xEE:
@@ -4452,7 +4705,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("INC $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("INC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			INC("absolute")
 		case 0x4C:
@@ -4468,11 +4721,15 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("JMP $%04X\n", int(operand2())<<8|int(operand1()))
+				fmt.Printf("JMP %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			// For AllSuiteA.bin 6502 opcode test suite
 			if memory[0x210] == 0xFF {
+				monitorExit()
 				fmt.Printf("\n\u001B[32;5mMemory address $210 == $%02X. All opcodes succesfully tested and passed!\u001B[0m\n", memory[0x210])
+				if executionGuided {
+					disassembleExecuted(os.Args[1])
+				}
 				os.Exit(0)
 			}
 			JMP("absolute")
@@ -4498,13 +4755,13 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("JSR $%04X\n", int(operand2())<<8|int(operand1()))
+				fmt.Printf("JSR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			// Push low byte of PC onto stack
-			memory[SP] = byte(PC >> 8)
+			writeMemory(int(SP), byte(PC >> 8))
 			SP--
 			// Push high byte of PC onto stack
-			memory[SP] = byte(PC & 0xFF)
+			writeMemory(int(SP), byte(PC & 0xFF))
 			SP--
 			// Set the program counter to the absolute address from the operands
 			PC = int(operand2())<<8 | int(operand1())
@@ -4526,7 +4783,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("LDA $%04X\n", uint16(operand2())<<8|uint16(operand1()))
+				fmt.Printf("LDA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LDA("absolute")
 		case 0xAE:
@@ -4544,7 +4801,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("LDX $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("LDX %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LDX("absolute")
 		case 0xAC:
@@ -4562,7 +4819,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("LDY $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("LDY %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LDY("absolute")
 		case 0x4E:
@@ -4586,7 +4843,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("LSR $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("LSR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LSR("absolute")
 		case 0x0D:
@@ -4605,7 +4862,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("ORA $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("ORA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ORA("absolute")
 		case 0x2E:
@@ -4627,7 +4884,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("ROL $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("ROL %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ROL("absolute")
 		case 0x6E:
@@ -4651,7 +4908,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("ROR $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("ROR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ROR("absolute")
 		case 0xED:
@@ -4676,7 +4933,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("SBC $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("SBC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			SBC("absolute")
 		case 0x8D:
@@ -4693,7 +4950,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("STA $%04X\n", uint16(operand2())<<8|uint16(operand1()))
+				fmt.Printf("STA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			STA("absolute")
 		case 0x8E:
@@ -4709,7 +4966,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("STX $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("STX %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			STX("absolute")
 		case 0x8C:
@@ -4725,7 +4982,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("STY $%02X%02X\n", operand2(), operand1())
+				fmt.Printf("STY %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			STY("absolute")
 
@@ -4763,7 +5020,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("ADC $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("ADC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ADC("absolutex")
 		case 0x3D:
@@ -4782,7 +5039,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("AND $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("AND %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			AND("absolutex")
 		case 0x1E:
@@ -4805,7 +5062,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("ASL $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("ASL %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ASL("absolutex")
 		case 0xDD:
@@ -4826,7 +5083,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("CMP $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("CMP %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			CMP("absolutex")
 		case 0xDE:
@@ -4845,7 +5102,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("DEC $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("DEC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 
 			DEC("absolutex")
@@ -4865,7 +5122,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("EOR $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("EOR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			EOR("absolutex")
 		case 0xFE:
@@ -4884,7 +5141,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("INC $%04X,X\n", int(operand2())<<8|int(operand1()))
+				fmt.Printf("INC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			INC("absolutex")
 		case 0xBD:
@@ -4904,7 +5161,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("LDA $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("LDA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LDA("absolutex")
 		case 0xBC:
@@ -4921,7 +5178,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("LDY $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("LDY %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LDY("absolutex")
 		case 0x5E:
@@ -4946,7 +5203,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("LSR $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("LSR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LSR("absolutex")
 		case 0x1D:
@@ -4965,7 +5222,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("ORA $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("ORA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ORA("absolutex")
 		case 0x3E:
@@ -4987,7 +5244,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("ROL $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("ROL %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ROL("absolutex")
 		case 0x7E:
@@ -5010,7 +5267,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("ROR $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("ROR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ROR("absolutex")
 		case 0xFD:
@@ -5034,7 +5291,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("SBC $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("SBC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			SBC("absolutex")
 		case 0x9D:
@@ -5050,7 +5307,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,X)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("STA $%02X%02X,X\n", operand2(), operand1())
+				fmt.Printf("STA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			STA("absolutex")
 
@@ -5086,7 +5343,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,Y)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("ADC $%04X,Y\n", int(operand2())<<8|int(operand1()))
+				fmt.Printf("ADC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ADC("absolutey")
 		case 0x39:
@@ -5105,7 +5362,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,Y)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("AND $%02X%02X,Y\n", operand2(), operand1())
+				fmt.Printf("AND %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			AND("absolutey")
 		case 0xD9:
@@ -5126,7 +5383,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,Y)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("CMP $%02X%02X,Y\n", operand2(), operand1())
+				fmt.Printf("CMP %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			CMP("absolutey")
 		case 0x59:
@@ -5145,7 +5402,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,Y)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("EOR $%02X%02X,Y\n", operand2(), operand1())
+				fmt.Printf("EOR %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			EOR("absolutey")
 		case 0xB9:
@@ -5164,7 +5421,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,Y)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("LDA $%02X%02X,Y\n", operand2(), operand1())
+				fmt.Printf("LDA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LDA("absolutey")
 		case 0xBE:
@@ -5182,7 +5439,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,Y)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("LDX $%02X%02X,Y\n", operand2(), operand1())
+				fmt.Printf("LDX %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			LDX("absolutey")
 		case 0x19:
@@ -5201,7 +5458,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,Y)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("ORA $%02X%02X,Y\n", operand2(), operand1())
+				fmt.Printf("ORA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			ORA("absolutey")
 		case 0xF9:
@@ -5225,7 +5482,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,Y)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("SBC $%02X%02X,Y\n", operand2(), operand1())
+				fmt.Printf("SBC %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			SBC("absolutey")
 		case 0x99:
@@ -5242,7 +5499,7 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute,Y)\t\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("STA $%02X%02X,Y\n", operand2(), operand1())
+				fmt.Printf("STA %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			STA("absolutey")
 		// Absolute Indirect addressing mode instructions
@@ -5259,11 +5516,12 @@ func execute() {
 				if printHex {
 					fmt.Printf(";; $%04x\t$%02x $%02x $%02x\t(Absolute Indirect)\n", PC, opcode(), operand1(), operand2())
 				}
-				fmt.Printf("JMP ($%04X)\n", int(operand2())<<8|int(operand1()))
+				fmt.Printf("JMP %s\n", formatOperand(opcodes[opcode()], bytecounter))
 			}
 			JMP("indirect")
 		}
 		//printMachineState()
 	}
+	monitorExit()
 	fmt.Printf("memory[0x210] = %04X\n", memory[0x210])
 }
@@ -0,0 +1,703 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Machine monitor
+//
+// The mon mode stops before the first instruction and reads commands in the style of Supermon and the VICE monitor.
+// Numbers are hex with an optional $, and an address may also be given as a label or symbol name. The program runs
+// with g until Ctrl-C returns to the prompt, and z or s runs it one instruction at a time.
+//
+// Step over and step out keep running until the stack is back at the depth they started from, so a subroutine
+// that calls itself, or a BRK or interrupt handler that runs in between, does not stop them early.
+
+var (
+	monitorInput    = bufio.NewScanner(os.Stdin)
+	monitorActive   = false // The monitor is called before every instruction
+	stepMode        = ""    // step, over, out, to or count while stepping, empty while the program runs
+	stepsLeft       = 0     // Steps still to run before stepping stops
+	stepDepth       uint    // Stack pointer when the step started
+	stepTarget      = -1    // Address a step over returns to or a run to stops at, or an instruction number
+	stepReturned    = false // A step out has run the return from its subroutine
+	pendingAddress  = -1    // Instruction the prompt moved the PC to, which runs before the monitor stops again
+	interrupted     atomic.Bool
+	stopReason      = ""        // Why the emulator stopped: step, pause, breakpoint, data breakpoint or exception
+	stopBreakpoint  *breakpoint // Breakpoint or watchpoint the emulator stopped at
+	memoryCursor    = 0         // Where m continues
+	disassemblyNext = 0         // Where d continues
+)
+
+var monitorHelp = []string{
+	"r [reg=value ...]          Show the registers or change PC, A, X, Y, SP and SR",
+	"m [start [end]]            Dump memory",
+	"d [start [end]]            Disassemble",
+	"g [address]                Run until Ctrl-C",
+	"z [count], s [count]       Step through instructions",
+	"n [count]                  Step over, running a JSR until it returns",
+	"ret                        Step out, running until the current subroutine returns",
+	"un address                 Run until the program reaches address",
+	"rs [count]                 Step backwards through the history",
+	"rc                         Run backwards to the previous breakpoint or write watchpoint",
+	"gi number                  Go to the point where number instructions have run, in decimal",
+	"f start end byte ...       Fill memory with a byte pattern",
+	"t start end destination    Transfer memory",
+	"c start end destination    Compare memory",
+	"h start end byte ...       Hunt for bytes, or for text in quotes",
+	"l \"file\" [address]         Load a binary, a PRG without an address",
+	"sv \"file\" start end        Save memory to a binary",
+	"xr address ...             Cross references to addresses in the file",
+	"bt                         Show the subroutines that have been called and not returned",
+	"bk [address [after count] [ignore count] [if condition]]",
+	"                           Add a breakpoint, or list them",
+	"w [read|write|change|access] start [end] [after count] [ignore count] [if condition]",
+	"                           Add a watchpoint, or list the breakpoints and watchpoints",
+	"del [number]               Delete a breakpoint or watchpoint, or all of them",
+	"enable number, disable number",
+	"                           Enable or disable a breakpoint or watchpoint",
+	"ignore number count        Pass the next hits of a breakpoint or watchpoint",
+	"cond number [condition]    Change or remove the condition of a breakpoint or watchpoint",
+	"command number [\"cmd; ...\"]",
+	"                           Run commands when a breakpoint or watchpoint stops, or remove them",
+	"pb \"file\"                  Run the monitor commands in a file",
+	"x                          Exit",
+	"Addresses and values are hex, counts and instruction numbers are decimal",
+}
+
+// startMonitor calls the monitor before every instruction, which stops at the first one in mon mode and otherwise
+// at breakpoints. Ctrl-C returns to the prompt rather than ending the emulator.
+func startMonitor() {
+	monitorActive, recordHistory = true, historyLimit > 0
+	if machineMonitor {
+		stepMode = "step"
+	}
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		for range interrupts {
+			interrupted.Store(true)
+		}
+	}()
+	if machineMonitor && !tuiMode && !dapMode && !viceMode {
+		fmt.Printf("Machine monitor, ? lists the commands\n\n")
+	}
+}
+
+// monitorInstruction is called before each opcode switch and enters the prompt when the emulator stops at the
+// instruction that switch handles
+func monitorInstruction(length int) {
+	info := opcodes[opcode()]
+	if info.length == 0 && length == 1 {
+		// No switch handles an undefined opcode, so the emulator could never move past it
+		monitorMessage("Undefined opcode $%02X at $%04X", opcode(), bytecounter)
+		stepMode, stepsLeft, stopReason, stopBreakpoint = "step", 0, "exception", nil
+		monitorStop()
+		return
+	}
+	if info.length != length {
+		return
+	}
+	if stepMode != "step" && instructionCounter&0x3FF == 0 {
+		if tuiMode {
+			tuiPoll()
+		} else if dapMode {
+			dapPoll()
+		} else if viceMode {
+			vicePoll()
+		}
+	}
+	if bytecounter == pendingAddress {
+		pendingAddress = -1
+		return
+	}
+	stopReason, stopBreakpoint = "step", nil
+	if interrupted.Swap(false) {
+		monitorMessage("Interrupted")
+		stepMode, stepsLeft, stopReason = "step", 0, "pause"
+	}
+	if b := breakpointHit(); b != nil {
+		monitorMessage("Breakpoint %d at $%04X, hit %d", b.number, bytecounter, b.hits)
+		stepsLeft, stopReason, stopBreakpoint = 0, "breakpoint", b
+	} else if watchStop != nil {
+		stepsLeft, stopReason, stopBreakpoint = 0, "data breakpoint", watchStop
+	} else if !stepFinished() {
+		return
+	} else if stepsLeft > 0 {
+		stepsLeft--
+		startStep(stepMode, -1)
+		return
+	}
+	watchStop = nil
+	if stopBreakpoint == nil || len(stopBreakpoint.commands) == 0 || !runBreakpointCommands(stopBreakpoint) {
+		monitorStop()
+	}
+	// A new PC may hold an instruction of another length, which the following switch starts without stopping
+	if opcodes[opcode()].length != length {
+		pendingAddress = bytecounter
+	}
+}
+
+// startStep starts a step of the given mode from the instruction at bytecounter, which is about to run
+func startStep(mode string, target int) {
+	stepMode, stepDepth, stepTarget, stepReturned = mode, SP, target, false
+	switch {
+	case mode == "over" && opcode() == 0x20:
+		// JSR pushes its own address and the RTS returns three bytes after it
+		stepTarget = bytecounter + 3
+	case mode == "out":
+		stepFinished()
+	}
+}
+
+// stepFinished reports whether the current step ends before the instruction at bytecounter. The stack pointer
+// tells a return to the level the step started from apart from a deeper one in a nested call or interrupt.
+func stepFinished() bool {
+	switch stepMode {
+	case "step":
+		return true
+	case "over":
+		return stepTarget < 0 || bytecounter == stepTarget && SP >= stepDepth
+	case "out":
+		if stepReturned {
+			return true
+		}
+		// RTS or RTI at the depth the step started from, or above it once the subroutine has pulled what it pushed
+		if (opcode() == 0x60 || opcode() == 0x40) && SP >= stepDepth {
+			stepReturned = true
+		}
+	case "to":
+		return bytecounter == stepTarget
+	case "count":
+		return instructionCounter >= stepTarget
+	}
+	return false
+}
+
+// monitorStop hands the stopped emulator to the prompt, to the full screen debugger in tui mode, to the editor
+// in dap mode or to the client of the VICE binary monitor in vice mode
+func monitorStop() {
+	if tuiMode {
+		tuiPrompt()
+	} else if dapMode {
+		dapPrompt()
+	} else if viceMode {
+		vicePrompt()
+	} else {
+		monitorPrompt()
+	}
+}
+
+// monitorExit gives back the terminal and ends a debug session before the emulator exits
+func monitorExit() {
+	restoreTerminal()
+	if dapMode {
+		dapFinish()
+	}
+}
+
+// monitorMessage reports an event of the running program on its own line, or on the status line of the full
+// screen debugger
+func monitorMessage(format string, args ...interface{}) {
+	text := fmt.Sprintf(format, args...)
+	if tuiMode {
+		tuiStatus = text
+		return
+	}
+	if dapMode {
+		dapOutput(text)
+		return
+	}
+	fmt.Printf("%s\n", text)
+}
+
+// monitorPrompt shows where the emulator stopped and runs commands until one resumes the program
+func monitorPrompt() {
+	printRegisters()
+	fmt.Printf("  instruction %d, %d cycles\n", instructionCounter, cycleCount)
+	monitorDisassemble(bytecounter)
+	disassemblyNext = bytecounter
+	for {
+		fmt.Printf("(C:$%04X) ", bytecounter)
+		if !monitorInput.Scan() {
+			fmt.Printf("\n")
+			os.Exit(0)
+		}
+		resume, err := monitorCommand(monitorInput.Text())
+		if err != nil {
+			fmt.Printf("%v\n", err)
+		}
+		if resume {
+			interrupted.Store(false)
+			return
+		}
+	}
+}
+
+// monitorCommand runs one command line and reports whether the program should resume
+func monitorCommand(line string) (bool, error) {
+	fields := monitorFields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	command, args := strings.ToLower(fields[0]), fields[1:]
+	switch command {
+	case "?", "help":
+		for _, text := range monitorHelp {
+			fmt.Printf("%s\n", text)
+		}
+	case "r":
+		if err := setRegisters(args); err != nil {
+			return false, err
+		}
+		printRegisters()
+	case "m":
+		start, end, err := monitorRange(args, memoryCursor, 0x7F)
+		if err != nil {
+			return false, err
+		}
+		dumpMemory(start, end)
+		memoryCursor = (end + 1) & 0xFFFF
+	case "d":
+		start, end, err := monitorRange(args, disassemblyNext, -1)
+		if err != nil {
+			return false, err
+		}
+		address := start
+		for count := 0; end >= 0 && address <= end || end < 0 && count < 16; count++ {
+			address += monitorDisassemble(address & 0xFFFF)
+		}
+		disassemblyNext = address & 0xFFFF
+	case "g":
+		if len(args) > 0 {
+			address, err := monitorValue(args[0])
+			if err != nil {
+				return false, err
+			}
+			PC, bytecounter = address, address
+		}
+		stepMode = ""
+		return true, nil
+	case "z", "s", "n", "next":
+		count := 1
+		if len(args) > 0 {
+			value, err := monitorCount(args[0])
+			if err != nil {
+				return false, err
+			}
+			if value == 0 {
+				return false, fmt.Errorf("the count must be at least 1")
+			}
+			count = value
+		}
+		stepsLeft = count - 1
+		if command == "n" || command == "next" {
+			startStep("over", -1)
+		} else {
+			startStep("step", -1)
+		}
+		return true, nil
+	case "ret", "return":
+		stepsLeft = 0
+		startStep("out", -1)
+		return true, nil
+	case "un", "until":
+		if len(args) == 0 {
+			return false, fmt.Errorf("usage: un address")
+		}
+		address, err := monitorValue(args[0])
+		if err != nil {
+			return false, err
+		}
+		stepsLeft = 0
+		startStep("to", address)
+		return true, nil
+	case "rs", "rc", "gi":
+		if !recordHistory {
+			return false, fmt.Errorf("the history is off, history= sets its length")
+		}
+		var err error
+		switch command {
+		case "rs":
+			count := 1
+			if len(args) > 0 {
+				if count, err = monitorCount(args[0]); err != nil {
+					return false, err
+				}
+			}
+			err = reverseStep(count)
+		case "rc":
+			err = reverseContinue()
+		case "gi":
+			var number int
+			if len(args) == 0 {
+				return false, fmt.Errorf("usage: gi instruction_number")
+			}
+			if number, err = strconv.Atoi(args[0]); err != nil {
+				return false, fmt.Errorf("the instruction number is decimal, as shown at the prompt")
+			}
+			var resume bool
+			if resume, err = goToInstruction(number); resume {
+				return true, nil
+			}
+		}
+		printRegisters()
+		fmt.Printf("  instruction %d, %d cycles\n", instructionCounter, cycleCount)
+		monitorDisassemble(bytecounter)
+		disassemblyNext = bytecounter
+		return false, err
+	case "f":
+		return false, fillMemory(args)
+	case "t", "c":
+		return false, transferMemory(command, args)
+	case "h":
+		return false, huntMemory(args)
+	case "l":
+		return false, loadMemory(args)
+	case "sv":
+		return false, saveMemory(args)
+	case "xr":
+		if len(args) == 0 {
+			return false, fmt.Errorf("xr needs an address")
+		}
+		buildXrefs(startAddress, linearStarts(startAddress, endAddress))
+		for _, arg := range args {
+			address, err := monitorValue(arg)
+			if err != nil {
+				return false, err
+			}
+			printXrefs(address)
+		}
+	case "bt", "backtrace":
+		printBacktrace()
+	case "bk", "break", "w", "watch", "del", "delete", "enable", "disable", "ignore", "cond", "condition", "command":
+		return false, breakpointCommand(command, args)
+	case "pb", "playback":
+		if len(args) == 0 {
+			return false, fmt.Errorf("usage: pb \"file\"")
+		}
+		return runScript(strings.Trim(strings.Join(args, " "), "\""))
+	case "x":
+		monitorExit()
+		os.Exit(0)
+	default:
+		return false, fmt.Errorf("unknown command %s, ? lists the commands", fields[0])
+	}
+	return false, nil
+}
+
+// monitorFields splits a command line at spaces, keeping quoted text together with its quotes
+func monitorFields(line string) []string {
+	var fields []string
+	field, quoted := "", false
+	for _, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			field += string(c)
+		case (c == ' ' || c == '\t') && !quoted:
+			if field != "" {
+				fields = append(fields, field)
+			}
+			field = ""
+		default:
+			field += string(c)
+		}
+	}
+	if field != "" {
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// monitorValue parses a hex number or the name of a label or symbol
+func monitorValue(text string) (int, error) {
+	if value, err := strconv.ParseUint(strings.TrimPrefix(text, "$"), 16, 16); err == nil {
+		return int(value), nil
+	}
+	if address, ok := addressOf(text); ok {
+		return address, nil
+	}
+	return 0, fmt.Errorf("bad value %s", text)
+}
+
+// monitorCount parses a count, such as the number of steps or of hits to ignore. Counts are decimal, unlike
+// addresses and values.
+func monitorCount(text string) (int, error) {
+	count, err := strconv.Atoi(text)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("%s is not a decimal count", text)
+	}
+	return count, nil
+}
+
+// addressOf returns the address of a label or symbol name
+func addressOf(name string) (int, bool) {
+	for _, names := range []map[int]string{labels, symbols} {
+		for address, n := range names {
+			if n == name {
+				return address, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// monitorValues parses every argument with monitorValue
+func monitorValues(args []string) ([]int, error) {
+	values := make([]int, len(args))
+	for i, arg := range args {
+		value, err := monitorValue(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// monitorRange returns the start and end of an optional address range. Without a start the range continues from
+// next, and without an end it spans length more bytes, or is -1 when the command chooses its own length.
+func monitorRange(args []string, next int, length int) (int, int, error) {
+	values, err := monitorValues(args)
+	if err != nil {
+		return 0, 0, err
+	}
+	start, end := next, -1
+	if len(values) > 0 {
+		start = values[0]
+	}
+	if len(values) > 1 {
+		end = values[1]
+	} else if length >= 0 {
+		end = start + length
+		if end > 0xFFFF {
+			end = 0xFFFF
+		}
+	}
+	if end >= 0 && end < start {
+		return 0, 0, fmt.Errorf("the range ends before it starts")
+	}
+	return start, end, nil
+}
+
+// monitorArguments parses a start and end address followed by at least extra more values
+func monitorArguments(args []string, extra int, usage string) (int, int, []int, error) {
+	if len(args) < 2+extra {
+		return 0, 0, nil, fmt.Errorf("usage: %s", usage)
+	}
+	values, err := monitorValues(args)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if values[1] < values[0] {
+		return 0, 0, nil, fmt.Errorf("the range ends before it starts")
+	}
+	return values[0], values[1], values[2:], nil
+}
+
+// printRegisters prints the registers in the layout of the Supermon R command
+func printRegisters() {
+	fmt.Printf("  ADDR A  X  Y  SP NV-BDIZC\n")
+	fmt.Printf(".;%04X %02X %02X %02X %02X %08b\n", bytecounter, A, X, Y, byte(SP), SR)
+}
+
+// setRegisters changes the registers named in arguments such as a=ff or pc=4000
+func setRegisters(args []string) error {
+	for _, arg := range args {
+		name, text, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("usage: r reg=value, such as r a=ff pc=4000")
+		}
+		value, err := monitorValue(text)
+		if err != nil {
+			return err
+		}
+		if strings.ToLower(name) != "pc" && value > 0xFF {
+			return fmt.Errorf("%s is a byte register", name)
+		}
+		switch strings.ToLower(name) {
+		case "pc":
+			PC, bytecounter = value, value
+		case "a":
+			A = byte(value)
+		case "x":
+			X = byte(value)
+		case "y":
+			Y = byte(value)
+		case "sp":
+			SP = 0x0100 | uint(value)
+		case "sr", "p", "fl":
+			SR = byte(value)
+		default:
+			return fmt.Errorf("unknown register %s", name)
+		}
+	}
+	return nil
+}
+
+// dumpMemory prints memory from start to end inclusive as hex and ASCII, 16 bytes to a line
+func dumpMemory(start int, end int) {
+	for line := start; line <= end; line += 16 {
+		hex, text := "", ""
+		for address := line; address < line+16 && address <= end; address++ {
+			value := memory[address]
+			hex += fmt.Sprintf(" %02X", value)
+			if value >= 0x20 && value < 0x7F {
+				text += string(rune(value))
+			} else {
+				text += "."
+			}
+		}
+		fmt.Printf(">C:%04X %-48s  %s\n", line, hex, text)
+	}
+}
+
+// monitorDisassemble prints the instruction at address with its label and returns its length
+func monitorDisassemble(address int) int {
+	if name, ok := addressName(address); ok {
+		fmt.Printf("%s:\n", name)
+	}
+	bytes, text, length := instructionText(address)
+	marker := " "
+	if address == bytecounter {
+		marker = ">"
+	}
+	fmt.Printf("%s.C:%04X  %-8s  %s\n", marker, address, bytes, text)
+	return length
+}
+
+// instructionText returns the bytes of the instruction at address as hex, the instruction and its length.
+// An undefined opcode is a one byte .BYTE directive.
+func instructionText(address int) (string, string, int) {
+	info := opcodes[memory[address]]
+	length, text := info.length, ""
+	if info.mnemonic == "" {
+		length, text = 1, fmt.Sprintf(".BYTE $%02X", memory[address])
+	} else {
+		text = formatInstruction(info, address)
+	}
+	var bytes []string
+	for i := 0; i < length; i++ {
+		bytes = append(bytes, fmt.Sprintf("%02X", memory[(address+i)&0xFFFF]))
+	}
+	return strings.Join(bytes, " "), text, length
+}
+
+// fillMemory repeats a byte pattern from start to end
+func fillMemory(args []string) error {
+	start, end, pattern, err := monitorArguments(args, 1, "f start end byte ...")
+	if err != nil {
+		return err
+	}
+	for address := start; address <= end; address++ {
+		memory[address] = byte(pattern[(address-start)%len(pattern)])
+	}
+	return nil
+}
+
+// transferMemory copies start to end to the destination with t, or lists the addresses that differ with c
+func transferMemory(command string, args []string) error {
+	start, end, rest, err := monitorArguments(args, 1, command+" start end destination")
+	if err != nil {
+		return err
+	}
+	destination := rest[0]
+	if destination+end-start > 0xFFFF {
+		return fmt.Errorf("the destination runs past $FFFF")
+	}
+	if command == "t" {
+		// copy handles overlapping ranges
+		copy(memory[destination:destination+end-start+1], memory[start:end+1])
+		return nil
+	}
+	differences := 0
+	for i := 0; i <= end-start; i++ {
+		if memory[start+i] != memory[destination+i] {
+			fmt.Printf("%04X %04X  %02X %02X\n", start+i, destination+i, memory[start+i], memory[destination+i])
+			differences++
+		}
+	}
+	fmt.Printf("%d bytes differ\n", differences)
+	return nil
+}
+
+// huntMemory lists the addresses from start to end where a byte sequence or quoted text begins
+func huntMemory(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("usage: h start end byte ... or h start end \"text\"")
+	}
+	start, end, _, err := monitorArguments(args[:2], 0, "h start end")
+	if err != nil {
+		return err
+	}
+	var pattern []byte
+	if strings.HasPrefix(args[2], "\"") {
+		pattern = []byte(strings.Trim(strings.Join(args[2:], " "), "\""))
+	} else {
+		values, err := monitorValues(args[2:])
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			pattern = append(pattern, byte(value))
+		}
+	}
+	found := 0
+	for address := start; address+len(pattern)-1 <= end; address++ {
+		if string(memory[address:address+len(pattern)]) == string(pattern) {
+			fmt.Printf(" %04X", address)
+			if found++; found%8 == 0 {
+				fmt.Printf("\n")
+			}
+		}
+	}
+	if found%8 != 0 {
+		fmt.Printf("\n")
+	}
+	fmt.Printf("%d found\n", found)
+	return nil
+}
+
+// loadMemory loads a file at an address, or as a PRG at the address in its first two bytes
+func loadMemory(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: l \"file\" [address]")
+	}
+	data, err := os.ReadFile(strings.Trim(args[0], "\""))
+	if err != nil {
+		return err
+	}
+	var address int
+	if len(args) > 1 {
+		if address, err = monitorValue(args[1]); err != nil {
+			return err
+		}
+	} else {
+		if len(data) < 2 {
+			return fmt.Errorf("a PRG starts with its load address")
+		}
+		address, data = int(data[0])|int(data[1])<<8, data[2:]
+	}
+	count := copy(memory[address:], data)
+	fmt.Printf("Loaded $%04X to $%04X\n", address, address+count-1)
+	return nil
+}
+
+// saveMemory saves start to end inclusive as a binary without a load address
+func saveMemory(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("usage: sv \"file\" start end")
+	}
+	start, end, _, err := monitorArguments(args[1:], 0, "sv \"file\" start end")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(strings.Trim(args[0], "\""), memory[start:end+1], 0644)
+}
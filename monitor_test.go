@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestMonitorStepCountsAreDecimal(t *testing.T) {
+	defer func() {
+		stepMode, stepsLeft, stepTarget = "", 0, -1
+	}()
+	memory = [65536]byte{}
+	for _, command := range []string{"z 10", "s 10", "n 10"} {
+		if _, err := monitorCommand(command); err != nil || stepsLeft != 9 {
+			t.Errorf("%q leaves %d steps, error %v, want 9", command, stepsLeft, err)
+		}
+	}
+	for _, command := range []string{"z 1A", "z $10", "z 0", "n -1"} {
+		if _, err := monitorCommand(command); err == nil {
+			t.Errorf("%q was accepted", command)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import "github.com/IntuitionAmiga/six5go2/disasm"
+
+// opcodeInfo describes a single 6502 opcode for the static disassembler
+type opcodeInfo struct {
+	mnemonic        string // Three letter mnemonic, empty for undefined opcodes
+	mode            string // Addressing mode
+	length          int    // Instruction length in bytes including the opcode
+	cycles          int    // Base cycle count
+	pageCrossCycles int    // Extra cycles when indexing or a taken branch crosses a page boundary
+}
+
+// opcodes maps every documented NMOS 6502 opcode to its mnemonic, addressing mode, length and timing, taken from
+// the decoder package. Undefined opcodes are left as the zero value and are printed as data by the disassembler.
+var opcodes = func() (table [256]opcodeInfo) {
+	for i, op := range disasm.Opcodes {
+		table[i] = opcodeInfo{op.Mnemonic, string(op.Mode), op.Length, op.Cycles, op.PageCrossCycles}
+	}
+	return table
+}()
+
+// modeNames gives the human readable addressing mode used in the hex opcode comments
+var modeNames = map[string]string{
+	IMPLIED:     "Implied",
+	ACCUMULATOR: "Accumulator",
+	IMMEDIATE:   "Immediate",
+	ZEROPAGE:    "Zero Page",
+	ZEROPAGEX:   "Zero Page,X",
+	ZEROPAGEY:   "Zero Page,Y",
+	ABSOLUTE:    "Absolute",
+	ABSOLUTEX:   "Absolute,X",
+	ABSOLUTEY:   "Absolute,Y",
+	INDIRECT:    "Absolute Indirect",
+	INDIRECTX:   "Indirect,X",
+	INDIRECTY:   "Indirect,Y",
+	RELATIVE:    "Relative",
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Platform packs
+//
+// A platform pack names the hardware registers and ROM entry points of a well known 6502 machine, so that
+// STA $D020 reads as STA VIC_BORDER and JSR $FFD2 as JSR CHROUT. The names are added as symbols after any symbol
+// files have been loaded, so a name from the user's own symbols always wins.
+
+var platforms = map[string]func() map[int]string{
+	"c64":    c64Names,
+	"vic20":  vic20Names,
+	"apple2": apple2Names,
+	"nes":    nesNames,
+	"atari":  atariNames,
+	"bbc":    bbcNames,
+}
+
+// platform is the name of the selected platform pack
+var platform string
+
+// platformNames returns the names of all platform packs for the usage message
+func platformNames() string {
+	var names []string
+	for name := range platforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "/")
+}
+
+// loadPlatform adds the names from the named platform pack to the symbols
+func loadPlatform(name string) (int, error) {
+	names, ok := platforms[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown platform %s, choose one of %s", name, platformNames())
+	}
+	count := 0
+	for address, symbol := range names() {
+		count += addSymbol(symbol, address)
+	}
+	return count, nil
+}
+
+// registerBlock names consecutive registers starting at base, with prefix in front of each name
+func registerBlock(names map[int]string, base int, prefix string, registers ...string) {
+	for i, register := range registers {
+		names[base+i] = prefix + register
+	}
+}
+
+// viaRegisters are the sixteen registers of a 6522 VIA
+var viaRegisters = []string{"PRB", "PRA", "DDRB", "DDRA", "T1CL", "T1CH", "T1LL", "T1LH", "T2CL", "T2CH", "SR", "ACR", "PCR", "IFR", "IER", "PRA_NH"}
+
+// ciaRegisters are the sixteen registers of a 6526 CIA
+var ciaRegisters = []string{"PRA", "PRB", "DDRA", "DDRB", "TALO", "TAHI", "TBLO", "TBHI", "TOD10", "TODSEC", "TODMIN", "TODHR", "SDR", "ICR", "CRA", "CRB"}
+
+// kernalNames are the entries of the Commodore KERNAL jump table, three bytes apart from $FF81
+var kernalNames = []string{"CINT", "IOINIT", "RAMTAS", "RESTOR", "VECTOR", "SETMSG", "SECOND", "TKSA", "MEMTOP",
+	"MEMBOT", "SCNKEY", "SETTMO", "ACPTR", "CIOUT", "UNTLK", "UNLSN", "LISTEN", "TALK", "READST", "SETLFS", "SETNAM",
+	"OPEN", "CLOSE", "CHKIN", "CHKOUT", "CLRCHN", "CHRIN", "CHROUT", "LOAD", "SAVE", "SETTIM", "RDTIM", "STOP",
+	"GETIN", "CLALL", "UDTIM", "SCREEN", "PLOT", "IOBASE"}
+
+// cbmKernal names the KERNAL jump table entries from first upwards and the RAM interrupt vectors
+func cbmKernal(names map[int]string, first int) {
+	for i, name := range kernalNames {
+		if address := 0xFF81 + 3*i; address >= first {
+			names[address] = name
+		}
+	}
+	names[0x0314] = "CINV"
+	names[0x0316] = "CBINV"
+	names[0x0318] = "NMINV"
+}
+
+func c64Names() map[int]string {
+	names := map[int]string{0x0000: "CPU_DDR", 0x0001: "CPU_PORT"}
+	registerBlock(names, 0xD000, "VIC_", "SP0X", "SP0Y", "SP1X", "SP1Y", "SP2X", "SP2Y", "SP3X", "SP3Y",
+		"SP4X", "SP4Y", "SP5X", "SP5Y", "SP6X", "SP6Y", "SP7X", "SP7Y", "MSIGX", "SCROLY", "RASTER", "LPENX", "LPENY",
+		"SPENA", "SCROLX", "YXPAND", "VMCSB", "IRQ", "IRQMASK", "SPBGPR", "SPMC", "XXPAND", "SPSPCL", "SPBGCL",
+		"BORDER", "BG0", "BG1", "BG2", "BG3", "SPMC0", "SPMC1", "SP0COL", "SP1COL", "SP2COL", "SP3COL", "SP4COL",
+		"SP5COL", "SP6COL", "SP7COL")
+	registerBlock(names, 0xD400, "SID_", "FRELO1", "FREHI1", "PWLO1", "PWHI1", "VCREG1", "ATDCY1", "SUREL1",
+		"FRELO2", "FREHI2", "PWLO2", "PWHI2", "VCREG2", "ATDCY2", "SUREL2",
+		"FRELO3", "FREHI3", "PWLO3", "PWHI3", "VCREG3", "ATDCY3", "SUREL3",
+		"CUTLO", "CUTHI", "RESON", "SIGVOL", "POTX", "POTY", "RANDOM", "ENV3")
+	registerBlock(names, 0xDC00, "CIA1_", ciaRegisters...)
+	registerBlock(names, 0xDD00, "CIA2_", ciaRegisters...)
+	cbmKernal(names, 0xFF81)
+	return names
+}
+
+func vic20Names() map[int]string {
+	names := map[int]string{}
+	registerBlock(names, 0x9000, "VIC_", "HORIZ", "VERT", "COLUMNS", "ROWS", "RASTER", "CHARMAP", "LPENX", "LPENY",
+		"POT1", "POT2", "VOICE1", "VOICE2", "VOICE3", "NOISE", "VOLUME", "BORDER")
+	registerBlock(names, 0x9110, "VIA1_", viaRegisters...)
+	registerBlock(names, 0x9120, "VIA2_", viaRegisters...)
+	// The VIC-20 jump table starts at RESTOR
+	cbmKernal(names, 0xFF8A)
+	return names
+}
+
+func apple2Names() map[int]string {
+	return map[int]string{
+		// Soft switches
+		0xC000: "KBD", 0xC010: "KBDSTRB", 0xC020: "TAPEOUT", 0xC030: "SPKR",
+		0xC050: "TXTCLR", 0xC051: "TXTSET", 0xC052: "MIXCLR", 0xC053: "MIXSET",
+		0xC054: "LOWSCR", 0xC055: "HISCR", 0xC056: "LORES", 0xC057: "HIRES",
+		0xC058: "SETAN0", 0xC059: "CLRAN0", 0xC05A: "SETAN1", 0xC05B: "CLRAN1",
+		0xC05C: "SETAN2", 0xC05D: "CLRAN2", 0xC05E: "SETAN3", 0xC05F: "CLRAN3",
+		0xC060: "TAPEIN", 0xC061: "BUTN0", 0xC062: "BUTN1", 0xC063: "BUTN2",
+		0xC064: "PADDL0", 0xC065: "PADDL1", 0xC066: "PADDL2", 0xC067: "PADDL3", 0xC070: "PTRIG",
+		// Monitor ROM entry points
+		0xF800: "PLOT", 0xF819: "HLINE", 0xF828: "VLINE", 0xF832: "CLRSCR", 0xF836: "CLRTOP",
+		0xF85F: "NEXTCOL", 0xF864: "SETCOL", 0xF941: "PRNTAX", 0xFB1E: "PREAD", 0xFB2F: "INIT",
+		0xFBDD: "BELL1", 0xFC22: "VTAB", 0xFC42: "CLREOP", 0xFC58: "HOME", 0xFC9C: "CLREOL",
+		0xFCA8: "WAIT", 0xFD0C: "RDKEY", 0xFD1B: "KEYIN", 0xFD6A: "GETLN", 0xFD8E: "CROUT",
+		0xFDDA: "PRBYTE", 0xFDE3: "PRHEX", 0xFDED: "COUT", 0xFDF0: "COUT1", 0xFE89: "SETKBD",
+		0xFE93: "SETVID", 0xFF3A: "BELL", 0xFF59: "OLDRST", 0xFF69: "MONZ",
+	}
+}
+
+func nesNames() map[int]string {
+	names := map[int]string{}
+	registerBlock(names, 0x2000, "", "PPUCTRL", "PPUMASK", "PPUSTATUS", "OAMADDR", "OAMDATA", "PPUSCROLL", "PPUADDR", "PPUDATA")
+	registerBlock(names, 0x4000, "", "SQ1_VOL", "SQ1_SWEEP", "SQ1_LO", "SQ1_HI", "SQ2_VOL", "SQ2_SWEEP", "SQ2_LO", "SQ2_HI",
+		"TRI_LINEAR", "APU_UNUSED1", "TRI_LO", "TRI_HI", "NOISE_VOL", "APU_UNUSED2", "NOISE_LO", "NOISE_HI",
+		"DMC_FREQ", "DMC_RAW", "DMC_START", "DMC_LEN", "OAMDMA", "SND_CHN", "JOY1", "JOY2")
+	return names
+}
+
+func atariNames() map[int]string {
+	names := map[int]string{
+		// Operating system shadow registers
+		0x0012: "RTCLOK", 0x022F: "SDMCTL", 0x0230: "SDLSTL", 0x0231: "SDLSTH",
+		0x02C4: "COLOR0", 0x02C5: "COLOR1", 0x02C6: "COLOR2", 0x02C7: "COLOR3", 0x02C8: "COLOR4",
+		// Operating system vectors
+		0xE456: "CIOV", 0xE459: "SIOV", 0xE45C: "SETVBV", 0xE45F: "SYSVBV", 0xE462: "XITVBV",
+		0xE474: "WARMSV", 0xE477: "COLDSV",
+	}
+	registerBlock(names, 0xD000, "", "HPOSP0", "HPOSP1", "HPOSP2", "HPOSP3", "HPOSM0", "HPOSM1", "HPOSM2", "HPOSM3",
+		"SIZEP0", "SIZEP1", "SIZEP2", "SIZEP3", "SIZEM", "GRAFP0", "GRAFP1", "GRAFP2", "GRAFP3", "GRAFM",
+		"COLPM0", "COLPM1", "COLPM2", "COLPM3", "COLPF0", "COLPF1", "COLPF2", "COLPF3", "COLBK",
+		"PRIOR", "VDELAY", "GRACTL", "HITCLR", "CONSOL")
+	registerBlock(names, 0xD200, "", "AUDF1", "AUDC1", "AUDF2", "AUDC2", "AUDF3", "AUDC3", "AUDF4", "AUDC4",
+		"AUDCTL", "STIMER", "SKREST", "POTGO", "POKEY_UNUSED", "SEROUT", "IRQEN", "SKCTL")
+	registerBlock(names, 0xD300, "", "PORTA", "PORTB", "PACTL", "PBCTL")
+	registerBlock(names, 0xD400, "", "DMACTL", "CHACTL", "DLISTL", "DLISTH", "HSCROL", "VSCROL", "ANTIC_UNUSED",
+		"PMBASE", "ANTIC_UNUSED2", "CHBASE", "WSYNC", "VCOUNT", "PENH", "PENV", "NMIEN", "NMIRES")
+	return names
+}
+
+func bbcNames() map[int]string {
+	names := map[int]string{
+		// Operating system vectors
+		0x0200: "USERV", 0x0202: "BRKV", 0x0204: "IRQ1V", 0x0206: "IRQ2V", 0x0208: "CLIV",
+		0x020A: "BYTEV", 0x020C: "WORDV", 0x020E: "WRCHV", 0x0210: "RDCHV",
+		// Hardware
+		0xFE00: "CRTC_ADDR", 0xFE01: "CRTC_DATA", 0xFE08: "ACIA_CTRL", 0xFE09: "ACIA_DATA",
+		0xFE10: "SERPROC", 0xFE20: "VIDULA_CTRL", 0xFE21: "VIDULA_PALETTE", 0xFE30: "ROMSEL",
+		// Operating system calls
+		0xFFC8: "NVRDCH", 0xFFCB: "NVWRCH", 0xFFCE: "OSFIND", 0xFFD1: "OSGBPB", 0xFFD4: "OSBPUT",
+		0xFFD7: "OSBGET", 0xFFDA: "OSARGS", 0xFFDD: "OSFILE", 0xFFE0: "OSRDCH", 0xFFE3: "OSASCI",
+		0xFFE7: "OSNEWL", 0xFFEE: "OSWRCH", 0xFFF1: "OSWORD", 0xFFF4: "OSBYTE", 0xFFF7: "OSCLI",
+	}
+	registerBlock(names, 0xFE40, "SYSVIA_", viaRegisters...)
+	registerBlock(names, 0xFE60, "USRVIA_", viaRegisters...)
+	return names
+}
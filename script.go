@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Monitor scripts
+//
+// moncommands= names a file of monitor commands that runs before the first instruction, as VICE runs the file given
+// with -moncommands, and pb runs a file from the prompt. Every line is a command, blank lines and lines starting
+// with ; are passed. A command that resumes the program, such as g or z, ends the script and the program starts the
+// way it says, so a script can set up breakpoints and then run to them. A script may play back another one, but not
+// one that is already running, and scripts nest at most maxScripts deep.
+//
+// A breakpoint or watchpoint may carry a list of commands that runs every time it stops the emulator. When one of
+// them resumes the program the emulator goes on without stopping, so "m 0200 020F; r; g" logs memory and the
+// registers at every hit.
+
+// maxScripts limits how deeply pb may nest scripts
+const maxScripts = 8
+
+var (
+	monitorScript = ""     // File of monitor commands from moncommands=
+	openScripts   []string // Scripts being run, outermost first
+)
+
+// runScript runs the monitor commands in a file and reports whether one of them resumed the program. A command that
+// fails is reported with its line and the script goes on.
+func runScript(filename string) (bool, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return false, err
+	}
+	for _, open := range openScripts {
+		if open == path {
+			return false, fmt.Errorf("%s is already running", filename)
+		}
+	}
+	if len(openScripts) >= maxScripts {
+		return false, fmt.Errorf("scripts are nested more than %d deep", maxScripts)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+	openScripts = append(openScripts, path)
+	defer func() { openScripts = openScripts[:len(openScripts)-1] }()
+	for number, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		resume, err := monitorCommand(line)
+		if err != nil {
+			fmt.Printf("%s line %d: %v\n", filename, number+1, err)
+		}
+		if resume {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseCommands splits a command list such as "m 0200 020F; r; g", with or without its quotes, into its commands
+func parseCommands(args []string) []string {
+	var commands []string
+	for _, command := range strings.Split(strings.Trim(strings.Join(args, " "), "\""), ";") {
+		if command = strings.TrimSpace(command); command != "" {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// runBreakpointCommands runs the commands of the breakpoint or watchpoint the emulator stopped at and reports
+// whether one of them resumed the program. The full screen debugger shows what they print on its status line, as
+// the terminal is its screen.
+func runBreakpointCommands(b *breakpoint) bool {
+	resume := false
+	run := func() {
+		for _, command := range b.commands {
+			var err error
+			if resume, err = monitorCommand(command); err != nil {
+				fmt.Printf("%s %d: %s: %v\n", b.title(), b.number, command, err)
+			}
+			if resume {
+				return
+			}
+		}
+	}
+	if tuiMode {
+		for _, line := range strings.Split(strings.TrimRight(monitorOutput(run), "\n"), "\n") {
+			if line != "" {
+				monitorMessage("%s", line)
+			}
+		}
+	} else {
+		run()
+	}
+	if resume {
+		interrupted.Store(false)
+	}
+	return resume
+}
+
+// monitorOutput runs f and returns what it prints
+func monitorOutput(f func()) string {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		f()
+		return ""
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+		output <- data
+	}()
+	f()
+	os.Stdout = stdout
+	writer.Close()
+	return string(<-output)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScriptRecursion(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, text string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	a := write("a.mon", "pb \""+filepath.Join(dir, "b.mon")+"\"\n")
+	write("b.mon", "pb \""+a+"\"\n")
+	var err error
+	output := monitorOutput(func() { _, err = runScript(a) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "a.mon is already running") {
+		t.Errorf("a script playing itself back printed %q", output)
+	}
+	if len(openScripts) != 0 {
+		t.Errorf("%d scripts are still open", len(openScripts))
+	}
+
+	// A chain of different scripts stops at the nesting limit
+	for i := 0; i <= maxScripts; i++ {
+		write(fmt.Sprintf("%d.mon", i), fmt.Sprintf("pb %s\n", filepath.Join(dir, fmt.Sprintf("%d.mon", i+1))))
+	}
+	output = monitorOutput(func() { _, err = runScript(filepath.Join(dir, "0.mon")) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, fmt.Sprintf("scripts are nested more than %d deep", maxScripts)) {
+		t.Errorf("a chain of scripts printed %q", output)
+	}
+}
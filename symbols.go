@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Symbol import
+//
+// Symbol files produced by the common toolchains give real names to addresses. The format of each line is
+// detected from its content so files can be mixed freely:
+//
+//	VICE label files			al C:1234 .name
+//	ca65 debug info (.dbg)			sym	id=0,name="name",...,val=0x1234,...
+//	ld65 map files (exports list)		name                      001234 RLA
+//	64tass and ACME label lists		name = $1234
+
+// symbols maps an address to the name imported from a symbol file
+var symbols = map[int]string{}
+
+// loadSymbols reads every symbol from filename and returns the number found
+func loadSymbols(filename string) (int, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	count := 0
+	exports := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			// The ld65 exports list runs from its header to the next blank line, which ends every map file section
+			exports = false
+			continue
+		case strings.HasPrefix(line, "Exports list"):
+			exports = true
+			continue
+		case exports:
+			if strings.HasPrefix(line, "---") {
+				continue
+			}
+			// Each line holds one or two "name value type" columns
+			for i := 0; i+1 < len(fields); i += 3 {
+				if value, err := strconv.ParseUint(fields[i+1], 16, 32); err == nil {
+					count += addSymbol(fields[i], int(value))
+				}
+			}
+		case fields[0] == "al" && len(fields) >= 3:
+			// VICE labels, the address may carry a memory space prefix such as C:
+			_, address, _ := strings.Cut(fields[1], ":")
+			if address == "" {
+				address = fields[1]
+			}
+			if value, err := strconv.ParseUint(address, 16, 32); err == nil {
+				count += addSymbol(strings.TrimPrefix(fields[2], "."), int(value))
+			}
+		case fields[0] == "sym" && strings.Contains(line, "name="):
+			name, value := "", -1
+			for _, attribute := range strings.Split(strings.TrimSpace(strings.TrimPrefix(line, "sym")), ",") {
+				key, text, _ := strings.Cut(attribute, "=")
+				switch key {
+				case "name":
+					name = strings.Trim(text, "\"")
+				case "val":
+					if parsed, err := parseNumber(text); err == nil {
+						value = parsed
+					}
+				}
+			}
+			if name != "" && value >= 0 {
+				count += addSymbol(name, value)
+			}
+		case len(fields) >= 3 && fields[1] == "=":
+			if value, err := parseNumber(fields[2]); err == nil {
+				count += addSymbol(fields[0], value)
+			}
+		}
+	}
+	return count, scanner.Err()
+}
+
+// addSymbol records name for address unless the address is already named or lies outside memory
+func addSymbol(name string, address int) int {
+	if address < 0 || address > 0xFFFF {
+		return 0
+	}
+	if _, ok := symbols[address]; ok {
+		return 0
+	}
+	symbols[address] = name
+	return 1
+}
+
+// parseNumber parses a $hex, 0xhex, %binary or decimal number
+func parseNumber(text string) (int, error) {
+	var value uint64
+	var err error
+	switch {
+	case strings.HasPrefix(text, "$"):
+		value, err = strconv.ParseUint(text[1:], 16, 32)
+	case strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X"):
+		value, err = strconv.ParseUint(text[2:], 16, 32)
+	case strings.HasPrefix(text, "%"):
+		value, err = strconv.ParseUint(text[1:], 2, 32)
+	default:
+		value, err = strconv.ParseUint(text, 10, 32)
+	}
+	return int(value), err
+}
+
+// addressName returns the label or symbol for address
+func addressName(address int) (string, bool) {
+	if name, ok := labels[address]; ok {
+		return name, true
+	}
+	name, ok := symbols[address]
+	return name, ok
+}
+
+// symbolicAddress returns address as the nearest symbol at or below it plus an offset, such as main+$12.
+// Without a symbol in the preceding page the plain hex address is returned.
+func symbolicAddress(address int) string {
+	for offset := 0; offset < 0x100 && address-offset >= 0; offset++ {
+		if name, ok := symbols[address-offset]; ok {
+			if offset == 0 {
+				return name
+			}
+			return fmt.Sprintf("%s+$%02X", name, offset)
+		}
+	}
+	return fmt.Sprintf("%04X", address)
+}
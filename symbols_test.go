@@ -0,0 +1,22 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadLd65Map(t *testing.T) {
+	defer func() {
+		symbols = map[int]string{}
+	}()
+	symbols = map[int]string{}
+	count, err := loadSymbols("testdata/ld65.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the exports are read, not the segments before them or the imports after them
+	want := map[int]string{0x0800: "__STACKSIZE__", 0x1000: "main", 0x100C: "print"}
+	if count != len(want) || !reflect.DeepEqual(symbols, want) {
+		t.Errorf("loaded %d symbols %v, want %v", count, symbols, want)
+	}
+}
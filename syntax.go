@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Assembler syntaxes
+//
+// The static disassemblers can write source for several popular cross assemblers. Each syntax describes how that
+// assembler spells its origin directive, comments, labels, data directives and how an absolute address below $0100
+// is forced to assemble as absolute rather than zero page, which keeps the reassembled binary byte-identical.
+
+type asmSyntax struct {
+	name          string
+	header        string // Lines printed before anything else, such as the CPU selection
+	origin        string // Origin directive format, takes the address
+	comment       string // Comment prefix
+	labelSuffix   string // Appended to a label where it is defined
+	equate        string // Equate format, takes the label and the address
+	byteDirective string
+	wordDirective string
+	textDirective string // Directive for quoted text, empty when text would not assemble to the same bytes
+	absPrefix     string // Written in front of an operand to force absolute addressing
+	absSuffix     string // Appended to the mnemonic to force absolute addressing
+	indent        string // Written in front of every instruction and directive
+	lowercase     bool   // Mnemonics are written in lower case
+}
+
+var syntaxes = map[string]asmSyntax{
+	// The original six5go2 listing format
+	"six5go2": {
+		name:          "six5go2",
+		origin:        " *= $%04X\n",
+		comment:       ";;",
+		labelSuffix:   ":",
+		equate:        "%s = $%04X\n",
+		byteDirective: ".byte",
+		wordDirective: ".word",
+		textDirective: ".text",
+		absPrefix:     "a:",
+	},
+	"ca65": {
+		name:          "ca65",
+		header:        ".setcpu \"6502\"\n",
+		origin:        "\t.org $%04X\n",
+		comment:       ";",
+		labelSuffix:   ":",
+		equate:        "%s = $%04X\n",
+		byteDirective: ".byte",
+		wordDirective: ".word",
+		textDirective: ".byte",
+		absPrefix:     "a:",
+		indent:        "\t",
+	},
+	"acme": {
+		name:          "acme",
+		origin:        "\t* = $%04X\n",
+		comment:       ";",
+		equate:        "%s = $%04X\n",
+		byteDirective: "!byte",
+		wordDirective: "!word",
+		textDirective: "!text",
+		absSuffix:     "+2",
+		indent:        "\t",
+	},
+	"64tass": {
+		name:          "64tass",
+		origin:        "\t* = $%04X\n",
+		comment:       ";",
+		equate:        "%s = $%04X\n",
+		byteDirective: ".byte",
+		wordDirective: ".word",
+		textDirective: ".text",
+		absPrefix:     "@w ",
+		indent:        "\t",
+	},
+	"dasm": {
+		name:          "dasm",
+		header:        "\tprocessor 6502\n",
+		origin:        "\torg $%04X\n",
+		comment:       ";",
+		equate:        "%s = $%04X\n",
+		byteDirective: "dc.b",
+		wordDirective: "dc.w",
+		textDirective: "dc.b",
+		absSuffix:     ".w",
+		indent:        "\t",
+	},
+	"kick": {
+		name:          "kick",
+		origin:        "\t* = $%04X\n",
+		comment:       "//",
+		labelSuffix:   ":",
+		equate:        ".label %s = $%04X\n",
+		byteDirective: ".byte",
+		wordDirective: ".word",
+		absSuffix:     ".abs",
+		indent:        "\t",
+		lowercase:     true,
+	},
+}
+
+// syntax is the assembler syntax used by the static disassemblers
+var syntax = syntaxes["six5go2"]
+
+// syntaxNames returns the names of all supported syntaxes for the usage message
+func syntaxNames() string {
+	var names []string
+	for name := range syntaxes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "/")
+}
+
+// forceAbsolute reports whether the instruction at address uses an absolute mode with an operand that an
+// assembler would otherwise shorten to zero page
+func forceAbsolute(info opcodeInfo, address int) bool {
+	switch info.mode {
+	case ABSOLUTE, ABSOLUTEX, ABSOLUTEY:
+		return readWord(address+1) < 0x100 && info.mnemonic != "JMP" && info.mnemonic != "JSR"
+	}
+	return false
+}
+
+// formatInstruction returns the instruction at address written in the current syntax
+func formatInstruction(info opcodeInfo, address int) string {
+	mnemonic := info.mnemonic
+	operand := formatOperand(info, address)
+	if forceAbsolute(info, address) {
+		mnemonic += syntax.absSuffix
+		operand = syntax.absPrefix + operand
+	}
+	if syntax.lowercase {
+		mnemonic = strings.ToLower(mnemonic)
+		operand = strings.NewReplacer(",X", ",x", ",Y", ",y").Replace(operand)
+	}
+	if operand == "" {
+		return mnemonic
+	}
+	return fmt.Sprintf("%s %s", mnemonic, operand)
+}
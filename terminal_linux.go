@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+// ioctl requests that read and set the terminal attributes
+const (
+	getTermios = syscall.TCGETS
+	setTermios = syscall.TCSETS
+)
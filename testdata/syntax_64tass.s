@@ -0,0 +1,32 @@
+sub_2000 = $2000
+
+	* = $1000
+
+L1000
+; $1000	$a9 $01 	(Immediate)
+	LDA #$01
+; $1002	$ad $10 $00 	(Absolute)
+	LDA @w $0010
+; $1005	$9d $20 $00 	(Absolute,X)
+	STA @w $0020,X
+; $1008	$20 $16 $10 	(Absolute)
+	JSR sub_1016
+; $100b	$20 $00 $20 	(Absolute)
+	JSR sub_2000
+; $100e	$d0 $f0 	(Relative)
+	BNE L1000
+; $1010	$4c $00 $10 	(Absolute)
+	JMP L1000
+; $1013			(Data)
+	.byte $02
+; $1014	$00 	(Implied)
+	BRK
+; $1015	$00 	(Implied)
+	BRK
+sub_1016
+; $1016	$60 	(Implied)
+	RTS
+	.text "HELLO",$00
+	.word sub_1016
+; $101f			(Data)
+	.byte $01,$02,$03
@@ -0,0 +1,34 @@
+.label sub_2000 = $2000
+
+	* = $1000
+
+L1000:
+// $1000	$a9 $01 	(Immediate)
+	lda #$01
+// $1002	$ad $10 $00 	(Absolute)
+	lda.abs $0010
+// $1005	$9d $20 $00 	(Absolute,X)
+	sta.abs $0020,x
+// $1008	$20 $16 $10 	(Absolute)
+	jsr sub_1016
+// $100b	$20 $00 $20 	(Absolute)
+	jsr sub_2000
+// $100e	$d0 $f0 	(Relative)
+	bne L1000
+// $1010	$4c $00 $10 	(Absolute)
+	jmp L1000
+// $1013			(Data)
+	.byte $02
+// $1014	$00 	(Implied)
+	brk
+// $1015	$00 	(Implied)
+	brk
+sub_1016:
+// $1016	$60 	(Implied)
+	rts
+// "HELLO."
+// $1017			(Data)
+	.byte $48,$45,$4C,$4C,$4F,$00
+	.word sub_1016
+// $101f			(Data)
+	.byte $01,$02,$03
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// Full screen debugger
+//
+// The tui mode is the machine monitor drawn as panes on the whole terminal: the registers with the ones that changed
+// in the last step or run highlighted, the disassembly around the cursor, which follows the PC when the emulator
+// stops, the stack page and a memory view that scrolls to any address. Keys replace the monitor commands. The
+// terminal is put into non canonical mode with the same raw ioctls getTermDim uses, and redrawn when its window
+// changes size.
+
+var (
+	tuiMode     = false
+	tuiTerminal *syscall.Termios // Terminal settings to restore, nil while the terminal is untouched
+	tuiKeys     = make(chan string, 16)
+	tuiResize   = make(chan os.Signal, 1)
+	tuiStatus   = ""         // Message on the status line, the key help when empty
+	tuiCursor   = 0          // Disassembly line that b and t act on
+	tuiTop      = 0          // First address of the disassembly pane
+	tuiMemory   = 0          // First address of the memory pane
+	tuiStride   = 16         // Bytes per line of the memory pane
+	tuiRows     = 0          // Lines of the memory pane
+	tuiLast     tuiRegisters // Registers when the program last resumed
+	tuiBefore   [65536]byte  // Memory when the program last resumed
+	tuiStopped  = false      // The screen shows the emulator stopped rather than running
+	tuiDrawn    time.Time
+
+	tuiSequences = [][2]string{{"[A", "up"}, {"[B", "down"}, {"[5~", "pgup"}, {"[6~", "pgdn"}}
+)
+
+type tuiRegisters struct {
+	A, X, Y, SR byte
+	SP          uint
+	PC          int
+}
+
+const (
+	tuiPlain   = ""
+	tuiHeading = "\033[1m"
+	tuiTitle   = "\033[7m"
+	tuiChanged = "\033[1;33m"
+	tuiCurrent = "\033[7m"
+	tuiBreak   = "\033[1;31m"
+	tuiFree    = "\033[2m"
+	tuiHelp    = "s step  n over  o out  t to cursor  u back  g run  p pause  b break  m memory  PgUp/PgDn  q quit"
+)
+
+// startTUI switches the terminal to non canonical mode without echo and to its alternate screen
+func startTUI() error {
+	var t syscall.Termios
+	if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, uintptr(0), uintptr(getTermios), uintptr(unsafe.Pointer(&t))); err != 0 {
+		return err
+	}
+	saved := t
+	// ISIG stays set, so Ctrl-C still pauses the program as it does in the monitor
+	t.Lflag &^= syscall.ICANON | syscall.ECHO
+	t.Cc[syscall.VMIN], t.Cc[syscall.VTIME] = 1, 0
+	if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, uintptr(0), uintptr(setTermios), uintptr(unsafe.Pointer(&t))); err != 0 {
+		return err
+	}
+	tuiTerminal = &saved
+	signal.Notify(tuiResize, syscall.SIGWINCH)
+	go readKeys()
+	fmt.Printf("\033[?1049h\033[?25l")
+	tuiMemory, tuiCursor = loadAddress&0xFFF0, bytecounter
+	tuiSnapshot()
+	return nil
+}
+
+// restoreTerminal puts back the screen and the settings the terminal had before the tui mode started
+func restoreTerminal() {
+	if tuiTerminal == nil {
+		return
+	}
+	fmt.Printf("\033[0m\033[?25h\033[?1049l")
+	syscall.Syscall(syscall.SYS_IOCTL, uintptr(0), uintptr(setTermios), uintptr(unsafe.Pointer(tuiTerminal)))
+	tuiTerminal = nil
+}
+
+// readKeys turns the bytes typed on the terminal into key names, escape sequences into up, down, pgup and pgdn
+func readKeys() {
+	buffer := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buffer)
+		if err != nil {
+			tuiKeys <- "q"
+			return
+		}
+		for i := 0; i < n; i++ {
+			switch buffer[i] {
+			case '\r', '\n':
+				tuiKeys <- "enter"
+			case 0x7F, '\b':
+				tuiKeys <- "backspace"
+			case 0x1B:
+				rest, key, skip := string(buffer[i+1:n]), "esc", 0
+				for _, sequence := range tuiSequences {
+					if strings.HasPrefix(rest, sequence[0]) {
+						key, skip = sequence[1], len(sequence[0])
+					}
+				}
+				if key == "esc" && strings.HasPrefix(rest, "[") {
+					// Other sequences are dropped up to their final byte
+					key, skip = "", 1
+					for skip < len(rest) && (rest[skip] < 0x40 || rest[skip] > 0x7E) {
+						skip++
+					}
+					skip++
+				}
+				i += skip
+				if key != "" {
+					tuiKeys <- key
+				}
+			default:
+				tuiKeys <- string(buffer[i])
+			}
+		}
+	}
+}
+
+// tuiPrompt shows the stopped emulator and handles keys until one resumes the program
+func tuiPrompt() {
+	tuiCursor, tuiStopped = bytecounter, true
+	for {
+		tuiDraw()
+		select {
+		case <-tuiResize:
+		case key := <-tuiKeys:
+			if tuiKey(key) {
+				interrupted.Store(false)
+				tuiStopped = false
+				tuiSnapshot()
+				tuiDraw()
+				return
+			}
+		}
+	}
+}
+
+// tuiPoll is called while the program runs to handle pause and quit and to redraw the screen a few times a second
+func tuiPoll() {
+	select {
+	case key := <-tuiKeys:
+		switch key {
+		case "p", " ":
+			interrupted.Store(true)
+		case "q":
+			restoreTerminal()
+			os.Exit(0)
+		}
+	case <-tuiResize:
+		tuiDraw()
+	default:
+	}
+	if time.Since(tuiDrawn) > 100*time.Millisecond {
+		tuiDraw()
+	}
+}
+
+// tuiKey handles a key of the stopped emulator and reports whether the program should resume
+func tuiKey(key string) bool {
+	tuiStatus = ""
+	switch key {
+	case "s", "z":
+		stepsLeft = 0
+		startStep("step", -1)
+		return true
+	case "n":
+		stepsLeft = 0
+		startStep("over", -1)
+		return true
+	case "o":
+		stepsLeft = 0
+		startStep("out", -1)
+		return true
+	case "t":
+		stepsLeft = 0
+		startStep("to", tuiCursor)
+		return true
+	case "g", "r":
+		stepMode = ""
+		return true
+	case "u":
+		if !recordHistory {
+			tuiStatus = "The history is off, history= sets its length"
+		} else if err := reverseStep(1); err != nil {
+			tuiStatus = fmt.Sprintf("%v", err)
+		}
+		tuiCursor = bytecounter
+	case "b":
+		toggleBreakpoint(tuiCursor)
+	case "up":
+		tuiCursor = disassemblyBefore(tuiCursor, 1)
+	case "down":
+		tuiCursor = (tuiCursor + instructionLength(tuiCursor)) & 0xFFFF
+	case "pgup":
+		tuiMemory = (tuiMemory - tuiRows*tuiStride) & 0xFFFF
+	case "pgdn":
+		tuiMemory = (tuiMemory + tuiRows*tuiStride) & 0xFFFF
+	case "m":
+		if text, ok := tuiInput("Memory address: "); ok {
+			address, err := monitorValue(text)
+			if err != nil {
+				tuiStatus = fmt.Sprintf("%v", err)
+			} else {
+				tuiMemory = address & 0xFFFF
+			}
+		}
+	case "q":
+		restoreTerminal()
+		os.Exit(0)
+	}
+	return false
+}
+
+// tuiInput reads a line on the status line, which Esc cancels
+func tuiInput(prompt string) (string, bool) {
+	text := ""
+	for {
+		tuiStatus = prompt + text
+		tuiDraw()
+		select {
+		case <-tuiResize:
+		case key := <-tuiKeys:
+			switch key {
+			case "enter":
+				tuiStatus = ""
+				return text, true
+			case "esc":
+				tuiStatus = ""
+				return "", false
+			case "backspace":
+				if len(text) > 0 {
+					text = text[:len(text)-1]
+				}
+			default:
+				if len(key) == 1 && key[0] >= ' ' {
+					text += key
+				}
+			}
+		}
+	}
+}
+
+// toggleBreakpoint deletes the breakpoints at address, or adds one when there is none
+func toggleBreakpoint(address int) {
+	var found []*breakpoint
+	for _, b := range breakpoints {
+		if b.kind == "exec" && b.address == address {
+			found = append(found, b)
+		}
+	}
+	for _, b := range found {
+		deleteBreakpoint(b)
+		tuiStatus = fmt.Sprintf("Breakpoint %d at $%04X deleted", b.number, address)
+	}
+	if len(found) > 0 {
+		return
+	}
+	b, err := addBreakpoint(false, []string{fmt.Sprintf("$%04X", address)})
+	if err != nil {
+		tuiStatus = fmt.Sprintf("%v", err)
+		return
+	}
+	tuiStatus = fmt.Sprintf("Breakpoint %d at $%04X", b.number, address)
+}
+
+// tuiSnapshot keeps the registers and memory as the program resumes, so that the next stop can show what changed
+func tuiSnapshot() {
+	tuiLast = tuiRegisters{A, X, Y, SR, SP, bytecounter}
+	tuiBefore = memory
+}
+
+// instructionLength returns the length of the instruction at address, 1 for an undefined opcode
+func instructionLength(address int) int {
+	if length := opcodes[memory[address&0xFFFF]].length; length > 0 {
+		return length
+	}
+	return 1
+}
+
+// disassemblyBefore returns the address count instructions before address. Code cannot be decoded backwards, so it
+// decodes forwards from the furthest start that lands on address.
+func disassemblyBefore(address int, count int) int {
+	for start := address - 3*count; start < address; start++ {
+		if start < 0 {
+			continue
+		}
+		var starts []int
+		at := start
+		for at < address {
+			starts = append(starts, at)
+			at += instructionLength(at)
+		}
+		if at != address {
+			continue
+		}
+		if len(starts) > count {
+			return starts[len(starts)-count]
+		}
+		return starts[0]
+	}
+	return address
+}
+
+// tuiRow is a line of the screen that keeps count of the columns its text takes, without the escape sequences
+type tuiRow struct {
+	text    strings.Builder
+	columns int
+	width   int
+}
+
+// add appends text in style, cut at the width of the screen
+func (r *tuiRow) add(style string, text string) {
+	text = cut(text, r.width-r.columns)
+	r.columns += utf8.RuneCountInString(text)
+	if style != tuiPlain {
+		text = style + text + "\033[0m"
+	}
+	r.text.WriteString(text)
+}
+
+// column pads the row with spaces up to column
+func (r *tuiRow) column(column int) {
+	if column > r.columns {
+		r.add(tuiPlain, strings.Repeat(" ", column-r.columns))
+	}
+}
+
+// cut returns text shortened to at most width characters
+func cut(text string, width int) string {
+	if utf8.RuneCountInString(text) > width {
+		return string([]rune(text)[:width])
+	}
+	return text
+}
+
+// tuiDraw redraws the whole screen
+func tuiDraw() {
+	width, height, err := getTermDim()
+	if err != nil || width < 40 || height < 12 {
+		fmt.Printf("\033[H\033[2JThe terminal is too small")
+		return
+	}
+	rows := make([]*tuiRow, height)
+	for i := range rows {
+		rows[i] = &tuiRow{width: width}
+	}
+	state := "running"
+	if tuiStopped {
+		state = "stopped"
+	}
+	rows[0].add(tuiTitle, fmt.Sprintf(" %-*s", width-1, fmt.Sprintf("six5go2  %s  %s", os.Args[1], state)))
+	drawRegisters(rows[1:5])
+
+	// The panes below the registers share what is left of the screen, the disassembly getting the larger part
+	body := height - 8
+	disassemblyRows := body * 3 / 5
+	tuiRows = body - disassemblyRows
+	stackColumn := width
+	if width >= 72 {
+		stackColumn = width - 29
+	}
+	rows[5].add(tuiHeading, "Disassembly")
+	drawDisassembly(rows[6:6+disassemblyRows], stackColumn)
+	if stackColumn < width {
+		rows[5].column(stackColumn)
+		rows[5].add(tuiHeading, fmt.Sprintf("Stack  SP=$%02X", SP&0xFF))
+		drawStack(rows[6:6+disassemblyRows], stackColumn)
+	}
+	tuiStride = 8
+	if width >= 74 {
+		tuiStride = 16
+	}
+	rows[6+disassemblyRows].add(tuiHeading, fmt.Sprintf("Memory $%04X", tuiMemory))
+	drawMemory(rows[7+disassemblyRows : height-1])
+	if tuiStatus != "" {
+		rows[height-1].add(tuiChanged, tuiStatus)
+	} else {
+		rows[height-1].add(tuiPlain, tuiHelp)
+	}
+
+	var screen strings.Builder
+	screen.WriteString("\033[H")
+	for i, row := range rows {
+		screen.WriteString(row.text.String())
+		screen.WriteString("\033[K")
+		if i < len(rows)-1 {
+			screen.WriteString("\r\n")
+		}
+	}
+	os.Stdout.WriteString(screen.String())
+	tuiDrawn = time.Now()
+}
+
+// drawRegisters fills four rows with the registers, highlighting the ones that changed, the counters and the
+// call chain
+func drawRegisters(rows []*tuiRow) {
+	rows[0].add(tuiHeading, "  ADDR A  X  Y  SP NV-BDIZC")
+	style := func(changed bool) string {
+		if changed {
+			return tuiChanged
+		}
+		return tuiPlain
+	}
+	rows[1].add(tuiPlain, "  ")
+	rows[1].add(style(bytecounter != tuiLast.PC), fmt.Sprintf("%04X", bytecounter))
+	for _, r := range []struct{ value, last byte }{{A, tuiLast.A}, {X, tuiLast.X}, {Y, tuiLast.Y}, {byte(SP), byte(tuiLast.SP)}} {
+		rows[1].add(tuiPlain, " ")
+		rows[1].add(style(r.value != r.last), fmt.Sprintf("%02X", r.value))
+	}
+	rows[1].add(tuiPlain, " ")
+	for bit := 7; bit >= 0; bit-- {
+		value := SR >> bit & 1
+		rows[1].add(style(value != tuiLast.SR>>bit&1), fmt.Sprintf("%d", value))
+	}
+	rows[2].add(tuiPlain, fmt.Sprintf("  instruction %d, %d cycles", instructionCounter, cycleCount))
+	if name, ok := addressName(bytecounter); ok {
+		rows[2].add(tuiPlain, "  at "+name)
+	}
+	rows[3].add(tuiPlain, "  "+callChain())
+}
+
+// drawDisassembly fills rows with the instructions around the cursor, the one at the PC in reverse video and
+// the ones with a breakpoint marked with *
+func drawDisassembly(rows []*tuiRow, width int) {
+	type line struct {
+		address int
+		label   string
+	}
+	lines := func(top int) []line {
+		var result []line
+		for address := top; len(result) < len(rows); address += instructionLength(address) {
+			if name, ok := addressName(address & 0xFFFF); ok {
+				result = append(result, line{-1, name + ":"})
+			}
+			result = append(result, line{address & 0xFFFF, ""})
+		}
+		return result[:len(rows)]
+	}
+	visible := func(shown []line) bool {
+		for _, l := range shown[:len(shown)-len(shown)/4] {
+			if l.address == tuiCursor {
+				return true
+			}
+		}
+		return false
+	}
+	shown := lines(tuiTop)
+	if !visible(shown) {
+		tuiTop = disassemblyBefore(tuiCursor, len(rows)/3)
+		shown = lines(tuiTop)
+	}
+	for i, l := range shown {
+		row := rows[i]
+		if l.address < 0 {
+			row.add(tuiPlain, cut(l.label, width-1))
+			continue
+		}
+		if breakpointSet[l.address] {
+			row.add(tuiBreak, "*")
+		} else {
+			row.add(tuiPlain, " ")
+		}
+		marker := " "
+		if l.address == tuiCursor {
+			marker = ">"
+		}
+		bytes, text, _ := instructionText(l.address)
+		text = cut(fmt.Sprintf("%s %04X  %-8s  %s", marker, l.address, bytes, text), width-2)
+		if l.address == bytecounter {
+			row.add(tuiCurrent, fmt.Sprintf("%-*s", width-2, text))
+		} else {
+			row.add(tuiPlain, text)
+		}
+	}
+}
+
+// drawStack fills rows from column with the stack page, eight bytes to a line. The bytes below the stack pointer
+// are free and drawn faint, the ones that changed since the program resumed are highlighted.
+func drawStack(rows []*tuiRow, column int) {
+	start := (0x100 | int(SP+1)&0xFF) &^ 7
+	if SP&0xFF == 0xFF {
+		start = 0x1F8
+	}
+	if last := 0x200 - 8*len(rows); start > last {
+		start = last
+	}
+	if start < 0x100 {
+		start = 0x100
+	}
+	for i, row := range rows {
+		address := start + 8*i
+		if address > 0x1FF {
+			break
+		}
+		row.column(column)
+		row.add(tuiPlain, fmt.Sprintf("%04X", address))
+		for j := 0; j < 8; j++ {
+			style := tuiPlain
+			switch {
+			case address+j <= int(0x100|SP&0xFF):
+				style = tuiFree
+			case memory[address+j] != tuiBefore[address+j]:
+				style = tuiChanged
+			}
+			row.add(tuiPlain, " ")
+			row.add(style, fmt.Sprintf("%02X", memory[address+j]))
+		}
+	}
+}
+
+// drawMemory fills rows with memory from tuiMemory as hex and text, highlighting the bytes that changed since the
+// program resumed
+func drawMemory(rows []*tuiRow) {
+	for i, row := range rows {
+		address := (tuiMemory + i*tuiStride) & 0xFFFF
+		row.add(tuiPlain, fmt.Sprintf("%04X ", address))
+		var text strings.Builder
+		for j := 0; j < tuiStride; j++ {
+			a := (address + j) & 0xFFFF
+			style := tuiPlain
+			if memory[a] != tuiBefore[a] {
+				style = tuiChanged
+			}
+			row.add(tuiPlain, " ")
+			row.add(style, fmt.Sprintf("%02X", memory[a]))
+			if memory[a] >= 0x20 && memory[a] < 0x7F {
+				text.WriteByte(memory[a])
+			} else {
+				text.WriteByte('.')
+			}
+		}
+		row.add(tuiPlain, "  "+text.String())
+	}
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// VICE binary monitor
+//
+// The vice mode serves the binary remote monitor protocol of the VICE emulators over TCP, so front-ends and editor
+// plugins written for x64sc can drive six5go2 instead. Every command is a header of a start byte, the API version,
+// the body length, a request id and the command type, followed by the body. Every response has the start byte, the
+// API version, the body length, the response type, an error code and the request id of the command, or $FFFFFFFF
+// for an event the emulator sends by itself. Numbers are little endian.
+//
+// The emulator stops before the first instruction and waits for a client. As in VICE, a command that arrives while
+// the program runs stops it, and the program only runs again after an exit, advance or execute until return
+// command. On every stop the client gets the registers and a stopped event, or a JAM event at an undefined opcode,
+// and on every resume a resumed event. Checkpoints are the breakpoints and watchpoints of the monitor, with the
+// condition syntax of the monitor, and a checkpoint that does not stop only reports its hits.
+
+type viceCommand struct {
+	api       byte
+	id        uint32
+	kind      byte
+	body      []byte
+	connected bool // A client has connected rather than sent a command
+}
+
+const (
+	viceEvent = 0xFFFFFFFF // Request id of the responses the emulator sends by itself
+
+	viceOK              = 0x00
+	viceMissing         = 0x01 // The checkpoint does not exist
+	viceBadMemspace     = 0x02
+	viceBadLength       = 0x80
+	viceBadParameter    = 0x81
+	viceBadVersion      = 0x82
+	viceUnknownCommand  = 0x83
+	viceGeneralFailure  = 0x8F
+	viceMemoryGet       = 0x01
+	viceMemorySet       = 0x02
+	viceCheckpointGet   = 0x11
+	viceCheckpointSet   = 0x12
+	viceCheckpointDel   = 0x13
+	viceCheckpointList  = 0x14
+	viceCheckpointFlip  = 0x15
+	viceConditionSet    = 0x22
+	viceRegistersGet    = 0x31
+	viceRegistersSet    = 0x32
+	viceJam             = 0x61
+	viceStopped         = 0x62
+	viceResumed         = 0x63
+	viceAdvance         = 0x71
+	viceExecuteToReturn = 0x73
+	vicePing            = 0x81
+	viceBanks           = 0x82
+	viceRegistersInfo   = 0x83
+	viceInfo            = 0x85
+	viceExit            = 0xAA
+	viceQuit            = 0xBB
+	viceReset           = 0xCC
+)
+
+// viceLengths holds the shortest body of each command with arguments
+var viceLengths = map[byte]int{
+	viceMemoryGet: 8, viceMemorySet: 8, viceCheckpointGet: 4, viceCheckpointSet: 8, viceCheckpointDel: 4,
+	viceCheckpointFlip: 5, viceConditionSet: 5, viceRegistersGet: 1, viceRegistersSet: 3, viceAdvance: 3,
+	viceRegistersInfo: 1, viceReset: 1,
+}
+
+// viceReplies holds the response type of the commands that answer with the response of another command
+var viceReplies = map[byte]byte{viceCheckpointSet: viceCheckpointGet, viceRegistersSet: viceRegistersGet}
+
+// viceRegisters are the registers of the main CPU with the ids VICE gives them
+var viceRegisters = []struct {
+	id   byte
+	name string
+	bits byte
+}{{0, "A", 8}, {1, "X", 8}, {2, "Y", 8}, {3, "PC", 16}, {4, "SP", 8}, {5, "FL", 8}}
+
+var (
+	viceMode      = false
+	listenAddress = "127.0.0.1:6502" // Where the vice mode listens, the address of the VICE binary monitor
+	viceStart     = 0                // Where the emulator started, which a reset returns to
+	viceWriter    io.Writer          // The connected client, nil while there is none
+	viceWriting   sync.Mutex
+	viceCommands  = make(chan *viceCommand, 16) // Commands from the client, a nil command when it has gone
+	viceQueued    []*viceCommand                // Commands that stopped the running program, handled once it stops
+	viceNoStop    = map[*breakpoint]bool{}      // Checkpoints that only report their hits
+	viceTemporary = map[*breakpoint]bool{}      // Checkpoints deleted once they have been hit
+)
+
+// startVice listens for clients of the binary monitor, one at a time, on the address of listen=
+func startVice() error {
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("VICE binary monitor listening on %s\n\n", listener.Addr())
+	viceStart = bytecounter
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			viceWriting.Lock()
+			viceWriter = connection
+			viceWriting.Unlock()
+			viceCommands <- &viceCommand{connected: true}
+			readVice(connection)
+			viceWriting.Lock()
+			viceWriter = nil
+			viceWriting.Unlock()
+		}
+	}()
+	return nil
+}
+
+// readVice passes every command from a client to the emulator until the client disconnects
+func readVice(connection io.ReadCloser) {
+	reader := bufio.NewReader(connection)
+	header := make([]byte, 11)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil || header[0] != 0x02 {
+			break
+		}
+		body := make([]byte, binary.LittleEndian.Uint32(header[2:6]))
+		if _, err := io.ReadFull(reader, body); err != nil {
+			break
+		}
+		viceCommands <- &viceCommand{api: header[1], id: binary.LittleEndian.Uint32(header[6:10]), kind: header[10],
+			body: body}
+	}
+	connection.Close()
+	viceCommands <- nil
+}
+
+// viceSend writes a response to the client, if one is connected
+func viceSend(kind byte, code byte, id uint32, body []byte) {
+	viceWriting.Lock()
+	defer viceWriting.Unlock()
+	if viceWriter == nil {
+		return
+	}
+	header := []byte{0x02, 0x02, 0, 0, 0, 0, kind, code, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(header[2:6], uint32(len(body)))
+	binary.LittleEndian.PutUint32(header[8:12], id)
+	viceWriter.Write(append(header, body...))
+}
+
+// vicePrompt tells the client where the emulator stopped and handles commands until one resumes the program
+func vicePrompt() {
+	if b := stopBreakpoint; b != nil {
+		viceSend(viceCheckpointGet, viceOK, viceEvent, viceCheckpoint(b))
+		noStop := viceNoStop[b]
+		if viceTemporary[b] {
+			delete(viceNoStop, b)
+			delete(viceTemporary, b)
+			deleteBreakpoint(b)
+		}
+		if noStop {
+			return
+		}
+	}
+	viceStop()
+	for {
+		var command *viceCommand
+		if len(viceQueued) > 0 {
+			command, viceQueued = viceQueued[0], viceQueued[1:]
+		} else {
+			command = <-viceCommands
+		}
+		if viceHandle(command) {
+			break
+		}
+	}
+	interrupted.Store(false)
+	viceSend(viceResumed, viceOK, viceEvent, viceWord(bytecounter))
+	if len(viceQueued) > 0 {
+		// The client sent more after the command that resumed, which stops the program again
+		stepMode, stepsLeft = "step", 0
+	}
+}
+
+// viceStop sends the registers and the stopped event, or the JAM event at an undefined opcode
+func viceStop() {
+	viceSend(viceRegistersGet, viceOK, viceEvent, viceRegisterValues())
+	if stopReason == "exception" {
+		viceSend(viceJam, viceOK, viceEvent, viceWord(bytecounter))
+	} else {
+		viceSend(viceStopped, viceOK, viceEvent, viceWord(bytecounter))
+	}
+}
+
+// vicePoll is called while the program runs and stops it when a command has come in
+func vicePoll() {
+	for {
+		select {
+		case command := <-viceCommands:
+			if command != nil && !command.connected {
+				viceQueued = append(viceQueued, command)
+				stepMode, stepsLeft = "step", 0
+			}
+		default:
+			return
+		}
+	}
+}
+
+// viceHandle answers a command and reports whether the program should resume
+func viceHandle(command *viceCommand) bool {
+	switch {
+	case command == nil:
+		// The program runs on without a client, as it would in VICE
+		stepMode, viceQueued = "", nil
+		return true
+	case command.connected:
+		viceStop()
+		return false
+	case command.api != 0x01 && command.api != 0x02:
+		viceSend(command.kind, viceBadVersion, command.id, nil)
+		return false
+	case len(command.body) < viceLengths[command.kind]:
+		viceSend(command.kind, viceBadLength, command.id, nil)
+		return false
+	}
+	body, code, resume := viceRun(command)
+	kind, ok := viceReplies[command.kind]
+	if !ok {
+		kind = command.kind
+	}
+	viceSend(kind, code, command.id, body)
+	if command.kind == viceReset && code == viceOK {
+		viceStop()
+	}
+	return resume
+}
+
+// viceRun runs a command and returns the body and error code of its response and whether the program should resume
+func viceRun(command *viceCommand) ([]byte, byte, bool) {
+	body := command.body
+	switch command.kind {
+	case viceMemoryGet, viceMemorySet:
+		// Side effects and banks do not apply, as memory has no I/O and only one bank
+		start, end := int(binary.LittleEndian.Uint16(body[1:3])), int(binary.LittleEndian.Uint16(body[3:5]))
+		if body[5] != 0 {
+			return nil, viceBadMemspace, false
+		}
+		if end < start {
+			return nil, viceBadParameter, false
+		}
+		if command.kind == viceMemorySet {
+			if len(body)-8 != end-start+1 {
+				return nil, viceBadLength, false
+			}
+			copy(memory[start:end+1], body[8:])
+			return nil, viceOK, false
+		}
+		return append(viceWord(end-start+1), memory[start:end+1]...), viceOK, false
+	case viceCheckpointGet, viceCheckpointDel, viceCheckpointFlip:
+		b, err := findBreakpoint(strconv.Itoa(int(binary.LittleEndian.Uint32(body))))
+		if err != nil {
+			return nil, viceMissing, false
+		}
+		switch command.kind {
+		case viceCheckpointGet:
+			return viceCheckpoint(b), viceOK, false
+		case viceCheckpointDel:
+			delete(viceNoStop, b)
+			delete(viceTemporary, b)
+			deleteBreakpoint(b)
+		case viceCheckpointFlip:
+			b.enabled = body[4] != 0
+			indexBreakpoints()
+		}
+		return nil, viceOK, false
+	case viceCheckpointSet:
+		b, code := viceAddCheckpoint(body)
+		if b == nil {
+			return nil, code, false
+		}
+		return viceCheckpoint(b), viceOK, false
+	case viceCheckpointList:
+		for _, b := range breakpoints {
+			viceSend(viceCheckpointGet, viceOK, command.id, viceCheckpoint(b))
+		}
+		return binary.LittleEndian.AppendUint32(nil, uint32(len(breakpoints))), viceOK, false
+	case viceConditionSet:
+		length := int(body[4])
+		if len(body) < 5+length {
+			return nil, viceBadLength, false
+		}
+		number := strconv.Itoa(int(binary.LittleEndian.Uint32(body)))
+		if _, err := findBreakpoint(number); err != nil {
+			return nil, viceMissing, false
+		}
+		if err := breakpointCommand("cond", []string{number, string(body[5 : 5+length])}); err != nil {
+			monitorMessage("Checkpoint %s: %v", number, err)
+			return nil, viceBadParameter, false
+		}
+		return nil, viceOK, false
+	case viceRegistersGet:
+		if body[0] != 0 {
+			return nil, viceBadMemspace, false
+		}
+		return viceRegisterValues(), viceOK, false
+	case viceRegistersSet:
+		if body[0] != 0 {
+			return nil, viceBadMemspace, false
+		}
+		count, items := int(binary.LittleEndian.Uint16(body[1:3])), body[3:]
+		for i := 0; i < count; i++ {
+			if len(items) < 4 || items[0] < 3 || len(items) < int(items[0])+1 {
+				return nil, viceBadLength, false
+			}
+			if !setViceRegister(items[1], int(binary.LittleEndian.Uint16(items[2:4]))) {
+				return nil, viceBadParameter, false
+			}
+			items = items[items[0]+1:]
+		}
+		return viceRegisterValues(), viceOK, false
+	case viceAdvance:
+		count := int(binary.LittleEndian.Uint16(body[1:3]))
+		if count == 0 {
+			count = 1
+		}
+		stepsLeft = count - 1
+		if body[0] != 0 {
+			startStep("over", -1)
+		} else {
+			startStep("step", -1)
+		}
+		return nil, viceOK, true
+	case viceExecuteToReturn:
+		stepsLeft = 0
+		startStep("out", -1)
+		return nil, viceOK, true
+	case vicePing:
+		return nil, viceOK, false
+	case viceBanks:
+		body := viceWord(2)
+		for i, name := range []string{"cpu", "ram"} {
+			body = append(body, byte(3+len(name)), byte(i), 0, byte(len(name)))
+			body = append(body, name...)
+		}
+		return body, viceOK, false
+	case viceRegistersInfo:
+		if body[0] != 0 {
+			return nil, viceBadMemspace, false
+		}
+		body := viceWord(len(viceRegisters))
+		for _, r := range viceRegisters {
+			body = append(body, byte(3+len(r.name)), r.id, r.bits, byte(len(r.name)))
+			body = append(body, r.name...)
+		}
+		return body, viceOK, false
+	case viceInfo:
+		// Clients check the version for the commands they may send, these are the commands of VICE 3.6
+		return []byte{4, 3, 6, 0, 0, 4, 0, 0, 0, 0}, viceOK, false
+	case viceExit:
+		stepMode = ""
+		return nil, viceOK, true
+	case viceQuit:
+		viceSend(viceQuit, viceOK, command.id, nil)
+		monitorExit()
+		fmt.Printf("Quit by the VICE binary monitor client\n")
+		os.Exit(0)
+	case viceReset:
+		reset()
+		A, X, Y = 0, 0, 0
+		PC, bytecounter, callStack = viceStart, viceStart, nil
+		return nil, viceOK, false
+	}
+	return nil, viceUnknownCommand, false
+}
+
+// viceAddCheckpoint adds a breakpoint or watchpoint from the body of a checkpoint set command and returns it, or
+// the error code
+func viceAddCheckpoint(body []byte) (*breakpoint, byte) {
+	start, end := int(binary.LittleEndian.Uint16(body[0:2])), int(binary.LittleEndian.Uint16(body[2:4]))
+	stop, enabled, operation, temporary := body[4] != 0, body[5] != 0, body[6], body[7] != 0
+	if len(body) > 8 && body[8] != 0 {
+		return nil, viceBadMemspace
+	}
+	if end < start {
+		return nil, viceBadParameter
+	}
+	var b *breakpoint
+	var err error
+	switch operation {
+	case 4:
+		if b, err = addBreakpoint(false, []string{fmt.Sprintf("$%04X", start)}); err == nil {
+			b.end = end
+		}
+	case 1, 2, 3:
+		kind := map[byte]string{1: "read", 2: "write", 3: "access"}[operation]
+		b, err = addBreakpoint(true, []string{kind, fmt.Sprintf("$%04X", start), fmt.Sprintf("$%04X", end)})
+	default:
+		// A checkpoint on execution and data access at once has no breakpoint to become
+		return nil, viceBadParameter
+	}
+	if err != nil {
+		return nil, viceGeneralFailure
+	}
+	b.enabled = enabled
+	viceNoStop[b], viceTemporary[b] = !stop, temporary
+	indexBreakpoints()
+	return b, viceOK
+}
+
+// viceCheckpoint returns the body of the checkpoint response for b
+func viceCheckpoint(b *breakpoint) []byte {
+	operation := map[string]byte{"exec": 4, "read": 1, "write": 2, "change": 2, "access": 3}[b.kind]
+	body := binary.LittleEndian.AppendUint32(nil, uint32(b.number))
+	body = append(body, viceBool(stopBreakpoint == b))
+	body = append(body, viceWord(b.address)...)
+	body = append(body, viceWord(b.end)...)
+	body = append(body, viceBool(!viceNoStop[b]), viceBool(b.enabled), operation, viceBool(viceTemporary[b]))
+	body = binary.LittleEndian.AppendUint32(body, uint32(b.hits))
+	body = binary.LittleEndian.AppendUint32(body, uint32(b.ignore))
+	return append(body, viceBool(b.condition != ""), 0)
+}
+
+// viceRegisterValues returns the body of the registers response
+func viceRegisterValues() []byte {
+	values := []int{int(A), int(X), int(Y), bytecounter, int(SP & 0xFF), int(SR)}
+	body := viceWord(len(viceRegisters))
+	for i, r := range viceRegisters {
+		body = append(body, 3, r.id)
+		body = append(body, viceWord(values[i])...)
+	}
+	return body
+}
+
+// setViceRegister changes the register with a VICE id and reports whether there is one
+func setViceRegister(id byte, value int) bool {
+	switch id {
+	case 0:
+		A = byte(value)
+	case 1:
+		X = byte(value)
+	case 2:
+		Y = byte(value)
+	case 3:
+		PC, bytecounter = value, value
+	case 4:
+		SP = 0x0100 | uint(value&0xFF)
+	case 5:
+		SR = byte(value)
+	default:
+		return false
+	}
+	return true
+}
+
+// viceWord returns a 16 bit value as little endian bytes
+func viceWord(value int) []byte {
+	return []byte{byte(value), byte(value >> 8)}
+}
+
+// viceBool returns 1 for true and 0 for false
+func viceBool(value bool) byte {
+	if value {
+		return 1
+	}
+	return 0
+}
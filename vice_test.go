@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// viceResponse is one response or event read back from the binary monitor
+type viceResponse struct {
+	kind byte
+	code byte
+	id   uint32
+	body []byte
+}
+
+// viceRequest encodes a command as a client sends it
+func viceRequest(kind byte, id uint32, body ...byte) []byte {
+	header := []byte{0x02, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, kind}
+	binary.LittleEndian.PutUint32(header[2:6], uint32(len(body)))
+	binary.LittleEndian.PutUint32(header[6:10], id)
+	return append(header, body...)
+}
+
+// viceExchange passes the requests through readVice and viceHandle and returns the responses written
+func viceExchange(t *testing.T, requests ...[]byte) []viceResponse {
+	t.Helper()
+	var written bytes.Buffer
+	viceWriter = &written
+	defer func() {
+		viceWriter = nil
+	}()
+	go readVice(io.NopCloser(bytes.NewReader(bytes.Join(requests, nil))))
+	for command := <-viceCommands; command != nil; command = <-viceCommands {
+		viceHandle(command)
+	}
+	var responses []viceResponse
+	data := written.Bytes()
+	for len(data) > 0 {
+		if len(data) < 12 || data[0] != 0x02 || data[1] != 0x02 {
+			t.Fatalf("bad response header % X", data)
+		}
+		length := int(binary.LittleEndian.Uint32(data[2:6]))
+		responses = append(responses, viceResponse{kind: data[6], code: data[7],
+			id: binary.LittleEndian.Uint32(data[8:12]), body: data[12 : 12+length]})
+		data = data[12+length:]
+	}
+	return responses
+}
+
+func TestViceCommands(t *testing.T) {
+	reset := func() {
+		breakpoints, nextBreakpoint = nil, 1
+		viceNoStop, viceTemporary = map[*breakpoint]bool{}, map[*breakpoint]bool{}
+		indexBreakpoints()
+	}
+	reset()
+	defer reset()
+	memory, stopBreakpoint = [65536]byte{}, nil
+	A, X, Y, SP, SR, PC, bytecounter = 0x01, 0x02, 0x03, 0x01FD, 0x24, 0x1000, 0x1000
+
+	checkpoint := []byte{
+		1, 0, 0, 0, // Number
+		0,          // Not the checkpoint the emulator stopped at
+		0x00, 0x10, // Start
+		0x0F, 0x10, // End
+		1, 1, 4, 0, // Stops, enabled, on execution, not temporary
+		0, 0, 0, 0, // Hits
+		0, 0, 0, 0, // Ignore count
+		0, 0, // No condition, main memory
+	}
+	registers := []byte{
+		6, 0,
+		3, 0, 0x42, 0x00, // A, as set below
+		3, 1, 0x02, 0x00, // X
+		3, 2, 0x03, 0x00, // Y
+		3, 3, 0x00, 0x10, // PC
+		3, 4, 0xFD, 0x00, // SP
+		3, 5, 0x24, 0x00, // FL
+	}
+	tests := []struct {
+		name      string
+		request   []byte
+		responses []viceResponse
+	}{
+		{"memory set", viceRequest(viceMemorySet, 1, 0, 0x00, 0x10, 0x02, 0x10, 0, 0, 0, 0xAA, 0xBB, 0xCC),
+			[]viceResponse{{viceMemorySet, viceOK, 1, []byte{}}}},
+		{"memory get", viceRequest(viceMemoryGet, 2, 0, 0x00, 0x10, 0x02, 0x10, 0, 0, 0),
+			[]viceResponse{{viceMemoryGet, viceOK, 2, []byte{3, 0, 0xAA, 0xBB, 0xCC}}}},
+		{"memory get from another memspace", viceRequest(viceMemoryGet, 3, 0, 0x00, 0x10, 0x02, 0x10, 1, 0, 0),
+			[]viceResponse{{viceMemoryGet, viceBadMemspace, 3, []byte{}}}},
+		{"memory get that is too short", viceRequest(viceMemoryGet, 4, 0, 0x00, 0x10),
+			[]viceResponse{{viceMemoryGet, viceBadLength, 4, []byte{}}}},
+		{"checkpoint set", viceRequest(viceCheckpointSet, 5, 0x00, 0x10, 0x0F, 0x10, 1, 1, 4, 0, 0),
+			[]viceResponse{{viceCheckpointGet, viceOK, 5, checkpoint}}},
+		{"checkpoint list", viceRequest(viceCheckpointList, 6),
+			[]viceResponse{{viceCheckpointGet, viceOK, 6, checkpoint}, {viceCheckpointList, viceOK, 6, []byte{1, 0, 0, 0}}}},
+		{"checkpoint get of a missing one", viceRequest(viceCheckpointGet, 7, 9, 0, 0, 0),
+			[]viceResponse{{viceCheckpointGet, viceMissing, 7, []byte{}}}},
+		{"registers set", viceRequest(viceRegistersSet, 8, 0, 1, 0, 3, 0, 0x42, 0x00),
+			[]viceResponse{{viceRegistersGet, viceOK, 8, registers}}},
+		{"registers get", viceRequest(viceRegistersGet, 9, 0),
+			[]viceResponse{{viceRegistersGet, viceOK, 9, registers}}},
+		{"unknown command", viceRequest(0xEE, 10),
+			[]viceResponse{{0xEE, viceUnknownCommand, 10, []byte{}}}},
+	}
+	for _, test := range tests {
+		responses := viceExchange(t, test.request)
+		if len(responses) != len(test.responses) {
+			t.Errorf("%s: got %d responses %v, want %v", test.name, len(responses), responses, test.responses)
+			continue
+		}
+		for i, want := range test.responses {
+			got := responses[i]
+			if got.kind != want.kind || got.code != want.code || got.id != want.id || !bytes.Equal(got.body, want.body) {
+				t.Errorf("%s: got type $%02X error $%02X id %d body % X, want type $%02X error $%02X id %d body % X",
+					test.name, got.kind, got.code, got.id, got.body, want.kind, want.code, want.id, want.body)
+			}
+		}
+	}
+	if !bytes.Equal(memory[0x1000:0x1003], []byte{0xAA, 0xBB, 0xCC}) || A != 0x42 {
+		t.Errorf("memory is % X and A is $%02X after setting them", memory[0x1000:0x1003], A)
+	}
+	if len(breakpoints) != 1 || breakpoints[0].address != 0x1000 || breakpoints[0].end != 0x100F {
+		t.Errorf("the checkpoint became %d breakpoints", len(breakpoints))
+	}
+}
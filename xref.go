@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Cross references
+//
+// Every decoded instruction that refers to an address is recorded against it with the kind of access. Indexed
+// modes are recorded against their base address and the zero page indirect modes as a pointer use of the zero
+// page location, since the effective address is only known at run time.
+
+type xref struct {
+	from int    // Address of the referring instruction
+	kind string // read, write, modify, branch, jump, call or pointer
+	mode string // Addressing mode of the referring instruction
+}
+
+var (
+	xrefs         = map[int][]xref{} // Every instruction that refers to an address
+	xrefReport    = false            // Append the cross reference report to the static disassembly
+	xrefAddresses []int              // Limit the report to these addresses
+)
+
+// accessKind returns how an instruction with a memory operand uses that memory
+func accessKind(info opcodeInfo) string {
+	switch info.mnemonic {
+	case "STA", "STX", "STY":
+		return "write"
+	case "INC", "DEC", "ASL", "LSR", "ROL", "ROR":
+		return "modify"
+	case "JSR":
+		return "call"
+	case "JMP":
+		if info.mode == INDIRECT {
+			return "pointer"
+		}
+		return "jump"
+	}
+	switch info.mode {
+	case RELATIVE:
+		return "branch"
+	case INDIRECTX, INDIRECTY:
+		return "pointer"
+	}
+	return "read"
+}
+
+// buildXrefs records the references made by every instruction in the range marked in opcodeStart
+func buildXrefs(start int, opcodeStart []bool) {
+	xrefs = map[int][]xref{}
+	for i, isStart := range opcodeStart {
+		if !isStart {
+			continue
+		}
+		info := opcodes[memory[start+i]]
+		target, ok := operandAddress(info, start+i)
+		if !ok {
+			continue
+		}
+		kind := accessKind(info)
+		xrefs[target] = append(xrefs[target], xref{from: start + i, kind: kind, mode: info.mode})
+		// The zero page indirect modes read a two byte pointer
+		if kind == "pointer" && info.mode != INDIRECT {
+			xrefs[(target+1)&0xFF] = append(xrefs[(target+1)&0xFF], xref{from: start + i, kind: kind, mode: info.mode})
+		}
+	}
+}
+
+// printXrefs prints the references to address as comment lines
+func printXrefs(address int) {
+	name, ok := addressName(address)
+	if ok {
+		writeLine("comment", -1, fmt.Sprintf("%s %s ($%04X)", syntax.comment, name, address))
+	} else {
+		writeLine("comment", -1, fmt.Sprintf("%s $%04X", syntax.comment, address))
+	}
+	if len(xrefs[address]) == 0 {
+		writeLine("comment", -1, fmt.Sprintf("%s     No references", syntax.comment))
+	}
+	for _, x := range xrefs[address] {
+		info := opcodes[memory[x.from]]
+		writeLine("comment", -1, fmt.Sprintf("%s     $%04X  %-24s %-8s (%s)", syntax.comment, x.from, formatInstruction(info, x.from), x.kind, modeNames[x.mode]))
+	}
+}
+
+// printXrefReport prints the references to the requested addresses, or every address that has any, in address order
+func printXrefReport() {
+	addresses := xrefAddresses
+	if len(addresses) == 0 {
+		for address := range xrefs {
+			addresses = append(addresses, address)
+		}
+		sort.Ints(addresses)
+	}
+	writeLine("", -1, "")
+	writeLine("comment", -1, syntax.comment+" Cross references")
+	writeLine("comment", -1, syntax.comment)
+	for _, address := range addresses {
+		printXrefs(address)
+	}
+}